@@ -0,0 +1,348 @@
+package fuelcell
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rsb/failure"
+)
+
+// ArgType describes how the value of a positional argument is parsed and
+// completed.
+type ArgType struct {
+	// Name describes the type in the help, it is omitted when empty.
+	Name string
+	// Parse converts the raw value, returning an error when it is invalid.
+	// The raw string is kept when Parse is nil.
+	Parse func(string) (interface{}, error)
+	// Complete returns the completions for the value being typed.
+	Complete func(toComplete string) ([]string, ShellCompDirective)
+}
+
+// The argument types available out of the box. FileArg and DirArg keep the
+// raw path, they only change how the value is completed.
+var (
+	StringArg = ArgType{
+		Parse: func(s string) (interface{}, error) { return s, nil },
+	}
+
+	IntArg = ArgType{
+		Name:     "int",
+		Parse:    func(s string) (interface{}, error) { return strconv.Atoi(s) },
+		Complete: noArgCompletions,
+	}
+
+	DurationArg = ArgType{
+		Name:     "duration",
+		Parse:    func(s string) (interface{}, error) { return time.ParseDuration(s) },
+		Complete: noArgCompletions,
+	}
+
+	FileArg = ArgType{
+		Name:  "file",
+		Parse: func(s string) (interface{}, error) { return s, nil },
+		Complete: func(string) ([]string, ShellCompDirective) {
+			return nil, ShellCompDirectiveDefault
+		},
+	}
+
+	DirArg = ArgType{
+		Name:  "dir",
+		Parse: func(s string) (interface{}, error) { return s, nil },
+		Complete: func(string) ([]string, ShellCompDirective) {
+			return nil, ShellCompDirectiveFilterDirs
+		},
+	}
+)
+
+// EnumArg accepts only one of values, which are also the completions.
+func EnumArg(values ...string) ArgType {
+	return ArgType{
+		Name: strings.Join(values, "|"),
+		Parse: func(s string) (interface{}, error) {
+			if !stringInSlice(s, values) {
+				return nil, fmt.Errorf("must be one of %s", strings.Join(values, ", "))
+			}
+			return s, nil
+		},
+		Complete: func(toComplete string) ([]string, ShellCompDirective) {
+			var completions []string
+			for _, v := range values {
+				if strings.HasPrefix(v, toComplete) {
+					completions = append(completions, v)
+				}
+			}
+			return completions, ShellCompDirectiveNoFileComp
+		},
+	}
+}
+
+func noArgCompletions(string) ([]string, ShellCompDirective) {
+	return nil, ShellCompDirectiveNoFileComp
+}
+
+// ArgSpec declares a named positional argument of a command, see
+// Cmd.ArgSpecs.
+type ArgSpec struct {
+	Name        string
+	Description string
+	// Optional arguments can be left out, they must follow the required ones.
+	Optional bool
+	// Variadic takes all the remaining args, only the last spec can be
+	// variadic.
+	Variadic bool
+	// Type parses and completes the value, defaults to StringArg.
+	Type ArgType
+}
+
+// parse converts the raw value with the type of the spec.
+func (s ArgSpec) parse(raw string) (interface{}, error) {
+	if s.Type.Parse == nil {
+		return raw, nil
+	}
+
+	return s.Type.Parse(raw)
+}
+
+// useLine formats the spec as it appears in the use line.
+func (s ArgSpec) useLine() string {
+	name := s.Name
+	if s.Variadic {
+		name += "..."
+	}
+
+	if s.Optional {
+		return "[" + name + "]"
+	}
+
+	return "<" + name + ">"
+}
+
+// HasArgSpecs determines if the positional arguments are declared with
+// ArgSpecs.
+func (c *Cmd) HasArgSpecs() bool {
+	return len(c.ArgSpecs) > 0
+}
+
+// ArgSpecsUsages returns the help section describing the ArgSpecs, one line
+// per argument.
+func (c *Cmd) ArgSpecsUsages() string {
+	padding := 0
+	for _, s := range c.ArgSpecs {
+		if l := len(s.useLine()); l > padding {
+			padding = l
+		}
+	}
+
+	var b strings.Builder
+	for _, s := range c.ArgSpecs {
+		line := "  " + rpad(s.useLine(), padding) + "   " + s.Description
+		if s.Type.Name != "" {
+			line += " (" + s.Type.Name + ")"
+		}
+		b.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+
+	return b.String()
+}
+
+// argSpecsUseLine returns the ArgSpecs as they appear in the use line.
+func (c *Cmd) argSpecsUseLine() string {
+	parts := make([]string, 0, len(c.ArgSpecs))
+	for _, s := range c.ArgSpecs {
+		parts = append(parts, s.useLine())
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// argSpecAt returns the spec of the positional argument at index i, nil
+// when there is none.
+func (c *Cmd) argSpecAt(i int) *ArgSpec {
+	n := len(c.ArgSpecs)
+	switch {
+	case n == 0:
+		return nil
+	case i < n:
+		return &c.ArgSpecs[i]
+	case c.ArgSpecs[n-1].Variadic:
+		return &c.ArgSpecs[n-1]
+	}
+
+	return nil
+}
+
+// validateArgSpecs checks the ArgSpecs are declared in a usable order. A
+// misplaced spec is a mistake of the program, not of its user, so it is
+// reported as a system failure.
+func (c *Cmd) validateArgSpecs() error {
+	optional := false
+	for i, s := range c.ArgSpecs {
+		if s.Variadic && i != len(c.ArgSpecs)-1 {
+			return failure.System("variadic arg (%s) of (%s) must be the last one", s.Name, c.Path())
+		}
+
+		if s.Optional {
+			optional = true
+			continue
+		}
+
+		if optional {
+			return failure.System("required arg (%s) of (%s) follows an optional one", s.Name, c.Path())
+		}
+	}
+
+	return nil
+}
+
+// parseArgSpecs validates args against the ArgSpecs and stores the parsed
+// values to be read with Arg and the typed getters.
+func (c *Cmd) parseArgSpecs(args []string) error {
+	c.argValues = nil
+	if !c.HasArgSpecs() {
+		return nil
+	}
+
+	if err := c.validateArgSpecs(); err != nil {
+		return err
+	}
+
+	required, max := 0, len(c.ArgSpecs)
+	for _, s := range c.ArgSpecs {
+		if !s.Optional {
+			required++
+		}
+	}
+
+	if c.ArgSpecs[max-1].Variadic {
+		max = -1
+	}
+
+	if len(args) < required || (max >= 0 && len(args) > max) {
+		return &ArgCountError{Path: c.Path(), Min: required, Max: max, Received: len(args)}
+	}
+
+	values := make(map[string]interface{}, len(c.ArgSpecs))
+	for i, raw := range args {
+		s := c.argSpecAt(i)
+		v, err := s.parse(raw)
+		if err != nil {
+			return &ArgParseError{Path: c.Path(), Name: s.Name, Arg: raw, Err: err}
+		}
+
+		if !s.Variadic {
+			values[s.Name] = v
+			continue
+		}
+
+		variadic, _ := values[s.Name].([]interface{})
+		values[s.Name] = append(variadic, v)
+	}
+
+	c.argValues = values
+	return nil
+}
+
+// completeArgSpecs completes the positional argument being typed with the
+// type of its spec.
+func completeArgSpecs(cmd *Cmd, args []string, toComplete string) ([]string, ShellCompDirective) {
+	s := cmd.argSpecAt(len(args))
+	if s == nil || s.Type.Complete == nil {
+		return nil, ShellCompDirectiveNoFileComp
+	}
+
+	return s.Type.Complete(toComplete)
+}
+
+// Arg returns the parsed value of the positional argument name, a
+// []interface{} for a variadic argument, or nil when it was not given.
+func (c *Cmd) Arg(name string) interface{} {
+	return c.argValues[name]
+}
+
+// ArgString returns the value of the positional argument name, which must
+// be parsed as a string, like StringArg, FileArg, DirArg and EnumArg.
+func (c *Cmd) ArgString(name string) (string, error) {
+	v, err := c.argValue(name)
+	if err != nil || v == nil {
+		return "", err
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return "", failure.InvalidParam("arg (%s) of (%s) is a (%T) not a string", name, c.Path(), v)
+	}
+
+	return s, nil
+}
+
+// ArgStrings returns the values of the variadic positional argument name,
+// which must be parsed as strings.
+func (c *Cmd) ArgStrings(name string) ([]string, error) {
+	v, err := c.argValue(name)
+	if err != nil || v == nil {
+		return nil, err
+	}
+
+	values, ok := v.([]interface{})
+	if !ok {
+		values = []interface{}{v}
+	}
+
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			return nil, failure.InvalidParam("arg (%s) of (%s) holds a (%T) not a string", name, c.Path(), value)
+		}
+		result = append(result, s)
+	}
+
+	return result, nil
+}
+
+// ArgInt returns the value of the positional argument name, which must be
+// parsed with IntArg.
+func (c *Cmd) ArgInt(name string) (int, error) {
+	v, err := c.argValue(name)
+	if err != nil || v == nil {
+		return 0, err
+	}
+
+	i, ok := v.(int)
+	if !ok {
+		return 0, failure.InvalidParam("arg (%s) of (%s) is a (%T) not an int", name, c.Path(), v)
+	}
+
+	return i, nil
+}
+
+// ArgDuration returns the value of the positional argument name, which must
+// be parsed with DurationArg.
+func (c *Cmd) ArgDuration(name string) (time.Duration, error) {
+	v, err := c.argValue(name)
+	if err != nil || v == nil {
+		return 0, err
+	}
+
+	d, ok := v.(time.Duration)
+	if !ok {
+		return 0, failure.InvalidParam("arg (%s) of (%s) is a (%T) not a duration", name, c.Path(), v)
+	}
+
+	return d, nil
+}
+
+// argValue returns the parsed value of the positional argument name, nil
+// when it was not given.
+func (c *Cmd) argValue(name string) (interface{}, error) {
+	for _, s := range c.ArgSpecs {
+		if s.Name == name {
+			return c.argValues[name], nil
+		}
+	}
+
+	return nil, failure.NotFound("arg (%s) is not declared on (%s)", name, c.Path())
+}
@@ -0,0 +1,222 @@
+package fuelcell
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rsb/failure"
+)
+
+// newScaleTree builds "app" with a "scale" command declaring its positional
+// arguments with ArgSpecs. Run stores the command in got.
+func newScaleTree(got **Cmd) *Cmd {
+	root := &Cmd{Use: "app", SilenceErrors: true, SilenceUsage: true}
+	scale := &Cmd{Use: "scale", Short: "scale the app", ArgSpecs: []ArgSpec{
+		{Name: "strategy", Description: "how to scale", Type: EnumArg("up", "down")},
+		{Name: "replicas", Description: "number of replicas", Type: IntArg},
+		{Name: "timeout", Description: "how long to wait", Type: DurationArg, Optional: true},
+		{Name: "services", Description: "services to scale", Optional: true, Variadic: true},
+	}}
+	scale.SetLifecycle(Lifecycle{Run: func(c *Cmd, _ []string) error {
+		*got = c
+		return nil
+	}})
+	root.Add(scale)
+	return root
+}
+
+func TestArgSpecs_Parse(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		replicas int
+		timeout  time.Duration
+		services []string
+	}{
+		{"required only", []string{"up", "3"}, 3, 0, nil},
+		{"optional", []string{"up", "3", "1m"}, 3, time.Minute, nil},
+		{"variadic", []string{"down", "1", "5s", "api", "web"}, 1, 5 * time.Second, []string{"api", "web"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var scale *Cmd
+			if _, _, _, err := executeC(newScaleTree(&scale), append([]string{"scale"}, tt.args...)...); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if strategy, err := scale.ArgString("strategy"); err != nil || strategy != tt.args[0] {
+				t.Errorf("ArgString(strategy) = %q, %v, want %q", strategy, err, tt.args[0])
+			}
+
+			if replicas, err := scale.ArgInt("replicas"); err != nil || replicas != tt.replicas {
+				t.Errorf("ArgInt(replicas) = %d, %v, want %d", replicas, err, tt.replicas)
+			}
+
+			if timeout, err := scale.ArgDuration("timeout"); err != nil || timeout != tt.timeout {
+				t.Errorf("ArgDuration(timeout) = %v, %v, want %v", timeout, err, tt.timeout)
+			}
+
+			if services, err := scale.ArgStrings("services"); err != nil || !reflect.DeepEqual(services, tt.services) {
+				t.Errorf("ArgStrings(services) = %q, %v, want %q", services, err, tt.services)
+			}
+
+			if tt.timeout == 0 && scale.Arg("timeout") != nil {
+				t.Errorf("Arg(timeout) = %v, want nil when it is not given", scale.Arg("timeout"))
+			}
+		})
+	}
+}
+
+func TestArgSpecs_Getters(t *testing.T) {
+	var scale *Cmd
+	if _, _, _, err := executeC(newScaleTree(&scale), "scale", "up", "3"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := scale.ArgInt("strategy"); !failure.IsInvalidParam(err) {
+		t.Errorf("ArgInt(strategy) error = %v, want an invalid param failure", err)
+	}
+
+	if _, err := scale.ArgDuration("replicas"); !failure.IsInvalidParam(err) {
+		t.Errorf("ArgDuration(replicas) error = %v, want an invalid param failure", err)
+	}
+
+	if _, err := scale.ArgString("replicas"); !failure.IsInvalidParam(err) {
+		t.Errorf("ArgString(replicas) error = %v, want an invalid param failure", err)
+	}
+
+	if _, err := scale.ArgString("bogus"); !failure.IsNotFound(err) {
+		t.Errorf("ArgString(bogus) error = %v, want a not found failure", err)
+	}
+}
+
+func TestArgSpecs_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		check func(error) bool
+	}{
+		{"missing required", []string{"up"}, func(err error) bool {
+			var e *ArgCountError
+			return errors.As(err, &e) && e.Min == 2 && e.Max == -1 && e.Received == 1
+		}},
+		{"invalid int", []string{"up", "many"}, func(err error) bool {
+			var e *ArgParseError
+			return errors.As(err, &e) && e.Name == "replicas" && e.Arg == "many"
+		}},
+		{"invalid enum", []string{"sideways", "3"}, func(err error) bool {
+			var e *ArgParseError
+			return errors.As(err, &e) && e.Name == "strategy"
+		}},
+		{"invalid duration", []string{"up", "3", "soon"}, func(err error) bool {
+			var e *ArgParseError
+			return errors.As(err, &e) && e.Name == "timeout"
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var scale *Cmd
+			_, _, _, err := executeC(newScaleTree(&scale), append([]string{"scale"}, tt.args...)...)
+			if !tt.check(err) || !IsUsageError(err) {
+				t.Errorf("error = %#v, want a usage error", err)
+			}
+
+			if scale != nil {
+				t.Error("Run was called with invalid args")
+			}
+		})
+	}
+}
+
+func TestArgSpecs_BadDeclaration(t *testing.T) {
+	tests := []struct {
+		name  string
+		specs []ArgSpec
+	}{
+		{"variadic not last", []ArgSpec{{Name: "a", Variadic: true}, {Name: "b"}}},
+		{"required after optional", []ArgSpec{{Name: "a", Optional: true}, {Name: "b"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := &Cmd{Use: "app", SilenceErrors: true, SilenceUsage: true, ArgSpecs: tt.specs}
+			root.SetLifecycle(Lifecycle{Run: func(*Cmd, []string) error { return nil }})
+
+			_, _, _, err := executeC(root, "x", "y")
+			if !failure.IsSystem(err) || IsUsageError(err) {
+				t.Errorf("error = %v, want a system failure that is not a usage error", err)
+			}
+
+			if code := root.ExitCode(err); code == ExitUsage {
+				t.Errorf("exit code = %d, want a code other than %d", code, ExitUsage)
+			}
+		})
+	}
+}
+
+func TestArgSpecs_Help(t *testing.T) {
+	var scale *Cmd
+	_, out, _, err := executeC(newScaleTree(&scale), "help", "scale")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, w := range []string{
+		"app scale <strategy> <replicas> [timeout] [services...]",
+		"Arguments:",
+		"<strategy>      how to scale (up|down)",
+		"<replicas>      number of replicas (int)",
+		"[services...]   services to scale\n",
+	} {
+		if !strings.Contains(out, w) {
+			t.Errorf("help does not contain %q:\n%s", w, out)
+		}
+	}
+}
+
+func TestArgSpecs_Completion(t *testing.T) {
+	newTree := func() *Cmd {
+		var scale *Cmd
+		root := newScaleTree(&scale)
+		root.Add(&Cmd{Use: "copy", ArgSpecs: []ArgSpec{{Name: "src", Type: FileArg}, {Name: "dst", Type: DirArg}}})
+		return root
+	}
+
+	runCompletionTests(t, newTree, []completionTest{
+		{
+			name:      "enum",
+			args:      []string{ShellCompRequestCmd, "scale", "d"},
+			want:      []string{"down", ":4"},
+			directive: "ShellCompDirectiveNoFileComp",
+		},
+		{
+			name:      "int",
+			args:      []string{ShellCompRequestCmd, "scale", "up", ""},
+			want:      []string{":4"},
+			directive: "ShellCompDirectiveNoFileComp",
+		},
+		{
+			name:      "string",
+			args:      []string{ShellCompRequestCmd, "scale", "up", "3", "1m", ""},
+			want:      []string{":4"},
+			directive: "ShellCompDirectiveNoFileComp",
+		},
+		{
+			name:      "file",
+			args:      []string{ShellCompRequestCmd, "copy", ""},
+			want:      []string{":0"},
+			directive: "ShellCompDirectiveDefault",
+		},
+		{
+			name:      "dir",
+			args:      []string{ShellCompRequestCmd, "copy", "a.txt", ""},
+			want:      []string{":16"},
+			directive: "ShellCompDirectiveFilterDirs",
+		},
+	})
+}
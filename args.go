@@ -1,10 +1,5 @@
 package fuelcell
 
-import (
-	"fmt"
-	"strings"
-)
-
 type PositionalArgs func(cmd *Cmd, args []string) error
 
 // Legacy arg validation has the following behaviour:
@@ -19,7 +14,7 @@ func legacyArgs(cmd *Cmd, args []string) error {
 
 	// root command with subcommands, do subcommand checking.
 	if !cmd.HasParent() && len(args) > 0 {
-		return fmt.Errorf("unknown command %q for %q", args[0], cmd.Path())
+		return newUnknownCommandError(cmd, args[0])
 	}
 
 	return nil
@@ -28,25 +23,26 @@ func legacyArgs(cmd *Cmd, args []string) error {
 // NoArgs returns an error if any args are included.
 func NoArgs(cmd *Cmd, args []string) error {
 	if len(args) > 0 {
-		return fmt.Errorf("unknown command %q for %q", args[0], cmd.Path())
+		return newUnknownCommandError(cmd, args[0])
 	}
 	return nil
 }
 
-// OnlyValidArgs returns an error if any args are not in the list of ValidArgs.
+// OnlyValidArgs returns an error if any args are not in the list of ValidArgs
+// or ArgAliases, or are CanonicalArgAliases of a value not in ValidArgs.
 func OnlyValidArgs(cmd *Cmd, args []string) error {
 	if len(cmd.ValidArgs) > 0 {
-		// Remove any description that may be included in ValidArgs.
-		// A description is following a tab character.
-		var validArgs []string
-		for _, v := range cmd.ValidArgs {
-			validArgs = append(validArgs, strings.Split(v, "\t")[0])
-		}
-
+		validArgs := cmd.validArgNames()
 		for _, v := range args {
-			if !stringInSlice(v, validArgs) {
-				return fmt.Errorf("invalid argument %q for %q", v, cmd.Path())
+			if stringInSlice(v, validArgs) || stringInSlice(v, cmd.ArgAliases) {
+				continue
 			}
+
+			if canonical, ok := cmd.CanonicalArgAliases[v]; ok && stringInSlice(canonical, validArgs) {
+				continue
+			}
+
+			return &InvalidArgError{Path: cmd.Path(), Arg: v, ValidArgs: validArgs}
 		}
 	}
 	return nil
@@ -61,7 +57,7 @@ func ArbitraryArgs(cmd *Cmd, args []string) error {
 func MinimumNArgs(n int) PositionalArgs {
 	return func(cmd *Cmd, args []string) error {
 		if len(args) < n {
-			return fmt.Errorf("requires at least %d arg(s), only received %d", n, len(args))
+			return &ArgCountError{Path: cmd.Path(), Min: n, Max: -1, Received: len(args)}
 		}
 		return nil
 	}
@@ -71,7 +67,7 @@ func MinimumNArgs(n int) PositionalArgs {
 func MaximumNArgs(n int) PositionalArgs {
 	return func(cmd *Cmd, args []string) error {
 		if len(args) > n {
-			return fmt.Errorf("accepts at most %d arg(s), received %d", n, len(args))
+			return &ArgCountError{Path: cmd.Path(), Min: 0, Max: n, Received: len(args)}
 		}
 		return nil
 	}
@@ -81,7 +77,7 @@ func MaximumNArgs(n int) PositionalArgs {
 func ExactArgs(n int) PositionalArgs {
 	return func(cmd *Cmd, args []string) error {
 		if len(args) != n {
-			return fmt.Errorf("accepts %d arg(s), received %d", n, len(args))
+			return &ArgCountError{Path: cmd.Path(), Min: n, Max: n, Received: len(args)}
 		}
 		return nil
 	}
@@ -90,6 +86,7 @@ func ExactArgs(n int) PositionalArgs {
 // ExactValidArgs returns an error if
 // there are not exactly N positional args OR
 // there are any positional args that are not in the `ValidArgs` field of `Command`
+// or its `ArgAliases` and `CanonicalArgAliases`
 func ExactValidArgs(n int) PositionalArgs {
 	return func(cmd *Cmd, args []string) error {
 		if err := ExactArgs(n)(cmd, args); err != nil {
@@ -103,7 +100,7 @@ func ExactValidArgs(n int) PositionalArgs {
 func RangeArgs(min int, max int) PositionalArgs {
 	return func(cmd *Cmd, args []string) error {
 		if len(args) < min || len(args) > max {
-			return fmt.Errorf("accepts between %d and %d arg(s), received %d", min, max, len(args))
+			return &ArgCountError{Path: cmd.Path(), Min: min, Max: max, Received: len(args)}
 		}
 		return nil
 	}
@@ -0,0 +1,139 @@
+package fuelcell
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPositionalArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator PositionalArgs
+		args      []string
+		want      error
+	}{
+		{"no args", NoArgs, nil, nil},
+		{"no args given one", NoArgs, []string{"x"}, &UnknownCommandError{Path: "app get", Command: "x"}},
+		{"only valid args", OnlyValidArgs, []string{"pods", "services"}, nil},
+		{"only valid args given another", OnlyValidArgs, []string{"pods", "nodes"}, &InvalidArgError{Path: "app get", Arg: "nodes", ValidArgs: []string{"pods", "services"}}},
+		{"arbitrary args", ArbitraryArgs, []string{"a", "b"}, nil},
+		{"minimum", MinimumNArgs(2), []string{"a", "b"}, nil},
+		{"below minimum", MinimumNArgs(2), []string{"a"}, &ArgCountError{Path: "app get", Min: 2, Max: -1, Received: 1}},
+		{"maximum", MaximumNArgs(1), []string{"a"}, nil},
+		{"above maximum", MaximumNArgs(1), []string{"a", "b"}, &ArgCountError{Path: "app get", Min: 0, Max: 1, Received: 2}},
+		{"exact", ExactArgs(1), []string{"a"}, nil},
+		{"not exact", ExactArgs(1), nil, &ArgCountError{Path: "app get", Min: 1, Max: 1, Received: 0}},
+		{"exact valid", ExactValidArgs(1), []string{"pods"}, nil},
+		{"exact valid given another", ExactValidArgs(1), []string{"nodes"}, &InvalidArgError{Path: "app get", Arg: "nodes", ValidArgs: []string{"pods", "services"}}},
+		{"range", RangeArgs(1, 2), []string{"a", "b"}, nil},
+		{"out of range", RangeArgs(1, 2), []string{"a", "b", "c"}, &ArgCountError{Path: "app get", Min: 1, Max: 2, Received: 3}},
+		{"match all", MatchAll(MinimumNArgs(1), OnlyValidArgs), []string{"nodes"}, &InvalidArgError{Path: "app get", Arg: "nodes", ValidArgs: []string{"pods", "services"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := &Cmd{Use: "app"}
+			get := &Cmd{Use: "get", ValidArgs: []string{"pods", "services"}}
+			root.Add(get)
+
+			err := tt.validator(get, tt.args)
+			if !reflect.DeepEqual(err, tt.want) {
+				t.Errorf("error = %#v, want %#v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPositionalArgs_UsageErrors(t *testing.T) {
+	var got []string
+	root := newTestTree(&got)
+	root.SilenceErrors = true
+	deploy, _, _ := root.Find([]string{"deploy"})
+	deploy.Args = ExactArgs(1)
+
+	_, _, errOut, err := executeC(root, "deploy")
+
+	var countErr *ArgCountError
+	if !errors.As(err, &countErr) || !IsUsageError(err) {
+		t.Fatalf("error = %v, want an ArgCountError reported as a usage error", err)
+	}
+
+	if countErr.Path != "app deploy" || countErr.Received != 0 {
+		t.Errorf("ArgCountError = %+v, want path app deploy and 0 received", countErr)
+	}
+
+	if errOut == "" {
+		t.Error("the usage was not printed for the usage error")
+	}
+}
+
+func TestArgAliases(t *testing.T) {
+	newTree := func(got *[]string) *Cmd {
+		root := &Cmd{Use: "app", SilenceErrors: true, SilenceUsage: true}
+		get := &Cmd{
+			Use:                 "get",
+			Args:                OnlyValidArgs,
+			ValidArgs:           []string{"pods\tlist pods", "services\tlist services"},
+			ArgAliases:          []string{"svc"},
+			CanonicalArgAliases: map[string]string{"po": "pods", "no": "nodes"},
+		}
+		get.SetLifecycle(Lifecycle{Run: func(_ *Cmd, args []string) error {
+			*got = args
+			return nil
+		}})
+		root.Add(get)
+		return root
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr *InvalidArgError
+	}{
+		{"valid arg", []string{"pods"}, []string{"pods"}, nil},
+		{"canonical alias", []string{"po"}, []string{"pods"}, nil},
+		{"mixed", []string{"services", "po"}, []string{"services", "pods"}, nil},
+		{"plain alias", []string{"svc"}, []string{"svc"}, nil},
+		{"alias of an invalid arg", []string{"no"}, nil, &InvalidArgError{Path: "app get", Arg: "no", ValidArgs: []string{"pods", "services"}}},
+		{"unknown arg", []string{"nodes"}, nil, &InvalidArgError{Path: "app get", Arg: "nodes", ValidArgs: []string{"pods", "services"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			_, _, _, err := executeC(newTree(&got), append([]string{"get"}, tt.args...)...)
+			if tt.wantErr != nil {
+				var invalid *InvalidArgError
+				if !errors.As(err, &invalid) || !reflect.DeepEqual(invalid, tt.wantErr) {
+					t.Errorf("error = %#v, want %#v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Run received %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	runCompletionTests(t, func() *Cmd { var got []string; return newTree(&got) }, []completionTest{
+		{
+			name:      "aliases are not suggested",
+			args:      []string{ShellCompNoDescRequestCmd, "get", ""},
+			want:      []string{"pods", "services", ":4"},
+			directive: "ShellCompDirectiveNoFileComp",
+		},
+		{
+			name:      "canonical value of a prefix",
+			args:      []string{ShellCompNoDescRequestCmd, "get", "p"},
+			want:      []string{"pods", ":4"},
+			directive: "ShellCompDirectiveNoFileComp",
+		},
+	})
+}
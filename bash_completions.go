@@ -1,5 +1,16 @@
 package fuelcell
 
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rsb/failure"
+	pflag "github.com/spf13/pflag"
+)
+
 // Annotations for Bash completion.
 const (
 	BashCompFilenameExt     = "fuelcell_annotation_bash_completion_filename_extensions"
@@ -7,3 +18,502 @@ const (
 	BashCompOneRequiredFlag = "fuelcell_annotation_bash_completion_one_required_flag"
 	BashCompSubdirsInDir    = "fuelcell_annotation_bash_completion_subdirs_in_dir"
 )
+
+// MarkFlagRequired instructs the various shell completion implementations to
+// prioritize the named flag when performing completion, and causes your
+// command to report an error if invoked without the flag.
+func (c *Cmd) MarkFlagRequired(name string) error {
+	return MarkFlagRequired(c.Flags(), name)
+}
+
+// MarkGlobalFlagRequired instructs the various shell completion implementations
+// to prioritize the named global flag when performing completion, and causes
+// your command to report an error if invoked without the flag.
+func (c *Cmd) MarkGlobalFlagRequired(name string) error {
+	return MarkFlagRequired(c.GlobalFlags(), name)
+}
+
+// MarkFlagFilename instructs the various shell completion implementations to
+// limit completions for the named flag to the specified file extensions.
+func (c *Cmd) MarkFlagFilename(name string, extensions ...string) error {
+	return MarkFlagFilename(c.Flags(), name, extensions...)
+}
+
+// MarkGlobalFlagFilename instructs the various shell completion
+// implementations to limit completions for the named global flag to the
+// specified file extensions.
+func (c *Cmd) MarkGlobalFlagFilename(name string, extensions ...string) error {
+	return MarkFlagFilename(c.GlobalFlags(), name, extensions...)
+}
+
+// MarkFlagDirname instructs the various shell completion implementations to
+// limit completions for the named flag to directory names.
+func (c *Cmd) MarkFlagDirname(name string) error {
+	return MarkFlagDirname(c.Flags(), name)
+}
+
+// MarkGlobalFlagDirname instructs the various shell completion
+// implementations to limit completions for the named global flag to
+// directory names.
+func (c *Cmd) MarkGlobalFlagDirname(name string) error {
+	return MarkFlagDirname(c.GlobalFlags(), name)
+}
+
+// MarkFlagCustom adds the BashCompCustom annotation to the named flag, if it
+// exists. The bash completion script will call the bash function f for the
+// flag.
+func (c *Cmd) MarkFlagCustom(name string, f string) error {
+	return MarkFlagCustom(c.Flags(), name, f)
+}
+
+// MarkFlagRequired instructs the various shell completion implementations to
+// prioritize the named flag when performing completion, and causes your
+// command to report an error if invoked without the flag.
+func MarkFlagRequired(flags *pflag.FlagSet, name string) error {
+	return setFlagAnnotation(flags, name, BashCompOneRequiredFlag, []string{"true"})
+}
+
+// MarkFlagFilename instructs the various shell completion implementations to
+// limit completions for the named flag to the specified file extensions.
+func MarkFlagFilename(flags *pflag.FlagSet, name string, extensions ...string) error {
+	return setFlagAnnotation(flags, name, BashCompFilenameExt, extensions)
+}
+
+// MarkFlagDirname instructs the various shell completion implementations to
+// limit completions for the named flag to directory names.
+func MarkFlagDirname(flags *pflag.FlagSet, name string) error {
+	return setFlagAnnotation(flags, name, BashCompSubdirsInDir, []string{})
+}
+
+// MarkFlagCustom adds the BashCompCustom annotation to the named flag, if it
+// exists. The bash completion script will call the bash function f for the
+// flag.
+func MarkFlagCustom(flags *pflag.FlagSet, name string, f string) error {
+	return setFlagAnnotation(flags, name, BashCompCustom, []string{f})
+}
+
+func setFlagAnnotation(flags *pflag.FlagSet, name, key string, values []string) error {
+	if flags.Lookup(name) == nil {
+		return failure.NotFound("flag %q does not exist", name)
+	}
+
+	return flags.SetAnnotation(name, key, values)
+}
+
+// GenBashCompletion generates the bash completion script, which relies on
+// the __complete command, and writes it to w. Descriptions are shown next
+// to the completions when includeDesc is set.
+func (c *Cmd) GenBashCompletion(w io.Writer, includeDesc bool) error {
+	buf := new(bytes.Buffer)
+	genBashComp(buf, c.Name(), c.bashCustomFlagCases(), c.bashCommandPathCases(), includeDesc)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// GenBashCompletionFile generates the bash completion script and writes it
+// to the file called filename.
+func (c *Cmd) GenBashCompletionFile(filename string, includeDesc bool) error {
+	outFile, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+
+	return c.GenBashCompletion(outFile, includeDesc)
+}
+
+// bashCustomFlagCases builds the case branches that call the bash functions
+// named by the BashCompCustom annotation. A branch matches the path of a
+// command followed by one of its flags, so the function only applies to the
+// commands the flag belongs to.
+func (c *Cmd) bashCustomFlagCases() string {
+	var b strings.Builder
+	var visit func(cmd *Cmd)
+	visit = func(cmd *Cmd) {
+		path := cmd.Path()
+		seen := map[string]bool{}
+		collect := func(f *pflag.Flag) {
+			if seen[f.Name] {
+				return
+			}
+			seen[f.Name] = true
+
+			fn, present := f.Annotations[BashCompCustom]
+			if !present || len(fn) == 0 {
+				return
+			}
+
+			pattern := fmt.Sprintf("%q", path+" --"+f.Name)
+			if f.Shorthand != "" {
+				pattern += fmt.Sprintf("|%q", path+" -"+f.Shorthand)
+			}
+
+			b.WriteString(fmt.Sprintf("    %s)\n        %s\n        return 0\n        ;;\n", pattern, fn[0]))
+		}
+
+		// the nearest declaration of a flag is the one that applies.
+		cmd.Flags().VisitAll(collect)
+		for p := cmd; p != nil; p = p.Parent() {
+			p.GlobalFlags().VisitAll(collect)
+		}
+
+		for _, sub := range cmd.Commands() {
+			visit(sub)
+		}
+	}
+	visit(c.Root())
+
+	return b.String()
+}
+
+// bashCommandPathCases builds the case branches that resolve the path of the
+// command being completed, one word of the command line at a time.
+func (c *Cmd) bashCommandPathCases() string {
+	var b strings.Builder
+	var visit func(cmd *Cmd)
+	visit = func(cmd *Cmd) {
+		for _, sub := range cmd.Commands() {
+			patterns := make([]string, 0, len(sub.Aliases)+1)
+			for _, name := range append([]string{sub.Name()}, sub.Aliases...) {
+				patterns = append(patterns, fmt.Sprintf("%q", cmd.Path()+" "+name))
+			}
+
+			b.WriteString(fmt.Sprintf("        %s)\n            path=%q\n            ;;\n", strings.Join(patterns, "|"), sub.Path()))
+			visit(sub)
+		}
+	}
+	visit(c.Root())
+
+	return b.String()
+}
+
+func genBashComp(buf io.StringWriter, name, customCases, pathCases string, includeDesc bool) {
+	compCmd := ShellCompRequestCmd
+	if !includeDesc {
+		compCmd = ShellCompNoDescRequestCmd
+	}
+
+	CheckWriteString(buf, fmt.Sprintf(`# bash completion for %-36[1]s -*- shell-script -*-
+
+__%[1]s_debug()
+{
+    if [[ -n ${BASH_COMP_DEBUG_FILE:-} ]]; then
+        echo "$*" >> "${BASH_COMP_DEBUG_FILE}"
+    fi
+}
+
+# Macs have bash3 for which the bash-completion package doesn't include
+# _init_completion. This is a minimal version of that function.
+__%[1]s_init_completion()
+{
+    COMPREPLY=()
+    _get_comp_words_by_ref "$@" cur prev words cword
+}
+
+# Prints the path of the command being completed, matching the words
+# typed before the current one against the names of the subcommands.
+__%[1]s_command_path()
+{
+    local path="%[1]s" word
+    for word in "${words[@]:1:${#words[@]}-2}"; do
+        case "${path} ${word}" in
+%[9]s        esac
+    done
+    echo "${path}"
+}
+
+# Flags annotated with a custom bash function are completed by calling
+# that function, which is responsible for filling COMPREPLY.
+__%[1]s_handle_custom_flag()
+{
+    local flag="${prev}"
+    if [[ "${cur}" == -*=* ]]; then
+        flag="${cur%%%%=*}"
+        cur="${cur#*=}"
+    fi
+
+    case "$(__%[1]s_command_path) ${flag}" in
+%[8]s    *)
+        return 1
+        ;;
+    esac
+}
+
+# This function calls the %[1]s program to obtain the completion
+# results and the directive.  It fills the 'out' and 'directive' vars.
+__%[1]s_get_completion_results() {
+    local requestComp lastParam lastChar args
+
+    # Prepare the command to request completions for the program.
+    # Calling ${words[0]} instead of directly %[1]s allows to handle aliases
+    args=("${words[@]:1}")
+    requestComp="${words[0]} %[2]s ${args[*]}"
+
+    lastParam=${words[$((${#words[@]}-1))]}
+    lastChar=${lastParam:$((${#lastParam}-1)):1}
+    __%[1]s_debug "lastParam ${lastParam}, lastChar ${lastChar}"
+
+    if [ -z "${cur}" ] && [ "${lastChar}" != "=" ]; then
+        # If the last parameter is complete (there is a space following it)
+        # We add an extra empty parameter so we can indicate this to the go method.
+        __%[1]s_debug "Adding extra empty parameter"
+        requestComp="${requestComp} ''"
+    fi
+
+    # When completing a flag with an = (e.g., %[1]s -n=<TAB>)
+    # bash focuses on the part after the =, so we need to remove
+    # the flag part from $cur
+    if [[ "${cur}" == -*=* ]]; then
+        cur="${cur#*=}"
+    fi
+
+    __%[1]s_debug "Calling ${requestComp}"
+    # Use eval to handle any environment variables and such
+    out=$(eval "${requestComp}" 2>/dev/null)
+
+    # Extract the directive integer at the very end of the output following a colon (:)
+    directive=${out##*:}
+    # Remove the directive
+    out=${out%%:*}
+    if [ "${directive}" = "${out}" ]; then
+        # There is not directive specified
+        directive=0
+    fi
+    __%[1]s_debug "The completion directive is: ${directive}"
+    __%[1]s_debug "The completions are: ${out[*]}"
+}
+
+__%[1]s_process_completion_results() {
+    local shellCompDirectiveError=%[3]d
+    local shellCompDirectiveNoSpace=%[4]d
+    local shellCompDirectiveNoFileComp=%[5]d
+    local shellCompDirectiveFilterFileExt=%[6]d
+    local shellCompDirectiveFilterDirs=%[7]d
+
+    if [ $((directive & shellCompDirectiveError)) -ne 0 ]; then
+        # Error code.  No completion.
+        __%[1]s_debug "Received error from custom completion go code"
+        return
+    else
+        if [ $((directive & shellCompDirectiveNoSpace)) -ne 0 ]; then
+            if [[ $(type -t compopt) = "builtin" ]]; then
+                __%[1]s_debug "Activating no space"
+                compopt -o nospace
+            else
+                __%[1]s_debug "No space directive not supported in this version of bash"
+            fi
+        fi
+        if [ $((directive & shellCompDirectiveNoFileComp)) -ne 0 ]; then
+            if [[ $(type -t compopt) = "builtin" ]]; then
+                __%[1]s_debug "Activating no file completion"
+                compopt +o default
+            else
+                __%[1]s_debug "No file completion directive not supported in this version of bash"
+            fi
+        fi
+    fi
+
+    if [ $((directive & shellCompDirectiveFilterFileExt)) -ne 0 ]; then
+        # File extension filtering
+        local fullFilter filter filteringCmd
+
+        # Do not use quotes around the $out variable or else newline
+        # characters will be kept.
+        for filter in ${out[*]}; do
+            fullFilter+="$filter|"
+        done
+
+        filteringCmd="_filedir $fullFilter"
+        __%[1]s_debug "File filtering command: $filteringCmd"
+        $filteringCmd
+    elif [ $((directive & shellCompDirectiveFilterDirs)) -ne 0 ]; then
+        # File completion for directories only
+
+        # Use printf to strip any trailing newline
+        local subdir
+        subdir=$(printf "%%s" "${out[0]}")
+        if [ -n "$subdir" ]; then
+            __%[1]s_debug "Listing directories in $subdir"
+            pushd "$subdir" >/dev/null 2>&1 && _filedir -d && popd >/dev/null 2>&1 || return
+        else
+            __%[1]s_debug "Listing directories in ."
+            _filedir -d
+        fi
+    else
+        __%[1]s_handle_completion_types
+    fi
+
+    __%[1]s_handle_special_char "$cur" :
+    __%[1]s_handle_special_char "$cur" =
+}
+
+__%[1]s_handle_completion_types() {
+    __%[1]s_debug "__%[1]s_handle_completion_types: COMP_TYPE is $COMP_TYPE"
+
+    case $COMP_TYPE in
+    37|42)
+        # Type: menu-complete/menu-complete-backward and insert-completions
+        # If the user requested inserting one completion at a time, or all
+        # completions at once on the command-line we must remove the descriptions.
+        local tab comp
+        tab=$(printf '\t')
+        while IFS='' read -r comp; do
+            # Strip any description
+            comp=${comp%%%%$tab*}
+            # Only consider the completions that match
+            comp=$(compgen -W "$comp" -- "$cur")
+            if [ -n "$comp" ]; then
+                COMPREPLY+=("$comp")
+            fi
+        done < <(printf "%%s\n" "${out[@]}")
+        ;;
+
+    *)
+        # Type: complete (normal completion)
+        __%[1]s_handle_standard_completion_case
+        ;;
+    esac
+}
+
+__%[1]s_handle_standard_completion_case() {
+    local tab comp
+    tab=$(printf '\t')
+
+    local longest=0
+    # Look for the longest completion so that we can format things nicely
+    while IFS='' read -r comp; do
+        # Strip any description before checking the length
+        comp=${comp%%%%$tab*}
+        # Only consider the completions that match
+        comp=$(compgen -W "$comp" -- "$cur")
+        if ((${#comp}>longest)); then
+            longest=${#comp}
+        fi
+    done < <(printf "%%s\n" "${out[@]}")
+
+    local completions=()
+    while IFS='' read -r comp; do
+        if [ -z "$comp" ]; then
+            continue
+        fi
+
+        __%[1]s_debug "Original comp: $comp"
+        comp="$(__%[1]s_format_comp_descriptions "$comp" "$longest")"
+        __%[1]s_debug "Final comp: $comp"
+        completions+=("$comp")
+    done < <(printf "%%s\n" "${out[@]}")
+
+    while IFS='' read -r comp; do
+        COMPREPLY+=("$comp")
+    done < <(compgen -W "${completions[*]}" -- "$cur")
+
+    # If there is a single completion left, remove the description text
+    if [ ${#COMPREPLY[*]} -eq 1 ]; then
+        __%[1]s_debug "COMPREPLY[0]: ${COMPREPLY[0]}"
+        comp="${COMPREPLY[0]%%%% *}"
+        __%[1]s_debug "Removed description from single completion, which is now: ${comp}"
+        COMPREPLY=()
+        COMPREPLY+=("$comp")
+    fi
+}
+
+__%[1]s_handle_special_char()
+{
+    local comp="$1"
+    local char=$2
+    if [[ "$comp" == *${char}* && "$COMP_WORDBREAKS" == *${char}* ]]; then
+        local word=${comp%%"${comp##*${char}}"}
+        local idx=${#COMPREPLY[*]}
+        while [[ $((--idx)) -ge 0 ]]; do
+            COMPREPLY[$idx]=${COMPREPLY[$idx]#"$word"}
+        done
+    fi
+}
+
+__%[1]s_format_comp_descriptions()
+{
+    local tab
+    tab=$(printf '\t')
+    local comp="$1"
+    local longest=$2
+
+    # Properly format the description string which follows a tab character if there is one
+    if [[ "$comp" == *$tab* ]]; then
+        desc=${comp#*$tab}
+        comp=${comp%%%%$tab*}
+
+        # $COLUMNS stores the current shell width.
+        # Remove an extra 4 because we add 2 spaces and 2 parentheses.
+        maxdesclength=$(( COLUMNS - longest - 4 ))
+
+        # Make sure we can fit a description of at least 8 characters
+        # if we are to align the descriptions.
+        if [[ $maxdesclength -gt 8 ]]; then
+            # Add the proper number of spaces to align the descriptions
+            for ((i = ${#comp} ; i < longest ; i++)); do
+                comp+=" "
+            done
+        else
+            # Don't pad the descriptions so we can fit more text after the completion
+            maxdesclength=$(( COLUMNS - ${#comp} - 4 ))
+        fi
+
+        # If there is enough space for any description text,
+        # truncate the descriptions that are too long for the shell width
+        if [ $maxdesclength -gt 0 ]; then
+            if [ ${#desc} -gt $maxdesclength ]; then
+                desc=${desc:0:$(( maxdesclength - 1 ))}
+                desc+="…"
+            fi
+            comp+="  ($desc)"
+        fi
+    fi
+
+    # Must use printf to escape all special characters
+    printf "%%q" "${comp}"
+}
+
+__start_%[1]s()
+{
+    local cur prev words cword split
+
+    COMPREPLY=()
+
+    # Call _init_completion from the bash-completion package
+    # to prepare the arguments properly
+    if declare -F _init_completion >/dev/null 2>&1; then
+        _init_completion -n "=:" || return
+    else
+        __%[1]s_init_completion -n "=:" || return
+    fi
+
+    __%[1]s_debug
+    __%[1]s_debug "========= starting completion logic =========="
+    __%[1]s_debug "cur is ${cur}, words[*] is ${words[*]}, #words[@] is ${#words[@]}, cword is $cword"
+
+    # The user could have moved the cursor backwards on the command-line.
+    # We need to trigger completion from the $cword location, so we need
+    # to truncate the command-line ($words) up to the $cword location.
+    words=("${words[@]:0:$cword+1}")
+    __%[1]s_debug "Truncated words[*]: ${words[*]},"
+
+    if __%[1]s_handle_custom_flag; then
+        return
+    fi
+
+    local out directive
+    __%[1]s_get_completion_results
+    __%[1]s_process_completion_results
+}
+
+if [[ $(type -t compopt) = "builtin" ]]; then
+    complete -o default -F __start_%[1]s %[1]s
+else
+    complete -o default -o nospace -F __start_%[1]s %[1]s
+fi
+
+# ex: ts=4 sw=4 et filetype=sh
+`, name, compCmd,
+		ShellCompDirectiveError, ShellCompDirectiveNoSpace, ShellCompDirectiveNoFileComp,
+		ShellCompDirectiveFilterFileExt, ShellCompDirectiveFilterDirs, customCases, pathCases))
+}
@@ -0,0 +1,212 @@
+package fuelcell
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rsb/failure"
+)
+
+func TestGenBashCompletion(t *testing.T) {
+	tests := []struct {
+		name        string
+		includeDesc bool
+		want        string
+	}{
+		{"with descriptions", true, `requestComp="${words[0]} __complete ${args[*]}"`},
+		{"without descriptions", false, `requestComp="${words[0]} __completeNoDesc ${args[*]}"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			if err := newCompletionTree().GenBashCompletion(buf, tt.includeDesc); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			script := buf.String()
+			for _, w := range []string{tt.want, "__start_app()", "complete -o default -F __start_app app"} {
+				if !strings.Contains(script, w) {
+					t.Errorf("script does not contain %q", w)
+				}
+			}
+		})
+	}
+}
+
+func TestGenBashCompletion_CustomFlag(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not installed")
+	}
+
+	root := newCompletionTree()
+	root.GlobalFlags().String("name", "", "name of the app")
+	deploy, _, _ := root.Find([]string{"deploy"})
+	deploy.Aliases = []string{"dp"}
+	deploy.Flags().StringP("name", "n", "", "name of the deployment")
+	if err := deploy.Flags().SetAnnotation("name", BashCompCustom, []string{"__deploy_names"}); err != nil {
+		t.Fatalf("SetAnnotation failed: %v", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "app.bash")
+	if err := root.GenBashCompletionFile(file, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		words string
+		want  string
+	}{
+		{"annotated flag", `app deploy --name ""`, "custom"},
+		{"annotated shorthand through an alias", `app dp -n ""`, "custom"},
+		{"annotated flag with a value", `app deploy --name=x`, "custom"},
+		{"same flag on another command", `app get --name ""`, "default"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := `source "$1"
+__deploy_names() { echo custom; }
+words=(` + tt.words + `)
+cur="${words[${#words[@]}-1]}"
+prev="${words[${#words[@]}-2]}"
+__app_handle_custom_flag || echo default`
+
+			out, err := exec.Command(bash, "-c", script, "bash", file).CombinedOutput()
+			if err != nil {
+				t.Fatalf("bash failed: %v\n%s", err, out)
+			}
+
+			if got := strings.TrimSpace(string(out)); got != tt.want {
+				t.Errorf("completion = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkFlagRequired(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"all set", []string{"deploy", "--env", "prod", "--region", "eu"}, nil},
+		{"local flag missing", []string{"deploy", "--region", "eu"}, []string{"env"}},
+		{"all missing", []string{"deploy"}, []string{"env", "region"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := newCompletionTree()
+			root.SilenceErrors = true
+			root.GlobalFlags().String("region", "", "region of the app")
+			if err := root.MarkGlobalFlagRequired("region"); err != nil {
+				t.Fatalf("MarkGlobalFlagRequired failed: %v", err)
+			}
+
+			deploy, _, _ := root.Find([]string{"deploy"})
+			if err := deploy.MarkFlagRequired("env"); err != nil {
+				t.Fatalf("MarkFlagRequired failed: %v", err)
+			}
+
+			_, _, _, err := executeC(root, tt.args...)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var required *RequiredFlagsError
+			if !errors.As(err, &required) {
+				t.Fatalf("error = %v, want a RequiredFlagsError", err)
+			}
+
+			if !reflect.DeepEqual(required.Flags, tt.want) {
+				t.Errorf("missing flags = %q, want %q", required.Flags, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkFlag_Completion(t *testing.T) {
+	newTree := func() *Cmd {
+		root := newCompletionTree()
+		root.GlobalFlags().String("config", "", "config file")
+		root.GlobalFlags().String("out", "", "output directory")
+		deploy, _, _ := root.Find([]string{"deploy"})
+		deploy.Flags().String("manifest", "", "manifest file")
+		deploy.Flags().String("workdir", "", "work directory")
+
+		for _, err := range []error{
+			root.MarkGlobalFlagFilename("config", "yaml", "yml"),
+			root.MarkGlobalFlagDirname("out"),
+			deploy.MarkFlagFilename("manifest", "json"),
+			deploy.MarkFlagDirname("workdir"),
+		} {
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+
+		return root
+	}
+
+	runCompletionTests(t, newTree, []completionTest{
+		{
+			name:      "filename",
+			args:      []string{ShellCompRequestCmd, "deploy", "--manifest", ""},
+			want:      []string{"json", ":8"},
+			directive: "ShellCompDirectiveFilterFileExt",
+		},
+		{
+			name:      "global filename",
+			args:      []string{ShellCompRequestCmd, "deploy", "--config", ""},
+			want:      []string{"yaml", "yml", ":8"},
+			directive: "ShellCompDirectiveFilterFileExt",
+		},
+		{
+			name:      "dirname",
+			args:      []string{ShellCompRequestCmd, "deploy", "--workdir", ""},
+			want:      []string{":16"},
+			directive: "ShellCompDirectiveFilterDirs",
+		},
+		{
+			name:      "global dirname",
+			args:      []string{ShellCompRequestCmd, "deploy", "--out", ""},
+			want:      []string{":16"},
+			directive: "ShellCompDirectiveFilterDirs",
+		},
+	})
+}
+
+func TestMarkFlag_Annotations(t *testing.T) {
+	root := newCompletionTree()
+	deploy, _, _ := root.Find([]string{"deploy"})
+
+	if err := deploy.MarkFlagCustom("env", "__app_envs"); err != nil {
+		t.Fatalf("MarkFlagCustom failed: %v", err)
+	}
+
+	if got := deploy.Flags().Lookup("env").Annotations[BashCompCustom]; !reflect.DeepEqual(got, []string{"__app_envs"}) {
+		t.Errorf("custom annotation = %q, want %q", got, []string{"__app_envs"})
+	}
+
+	for name, mark := range map[string]func(string) error{
+		"MarkFlagRequired":       deploy.MarkFlagRequired,
+		"MarkGlobalFlagRequired": deploy.MarkGlobalFlagRequired,
+		"MarkFlagDirname":        deploy.MarkFlagDirname,
+		"MarkFlagCustom":         func(n string) error { return deploy.MarkFlagCustom(n, "f") },
+	} {
+		if err := mark("bogus"); !failure.IsNotFound(err) {
+			t.Errorf("%s(bogus) = %v, want a not found failure", name, err)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package fuelcell
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/rsb/failure"
 	flag "github.com/spf13/pflag"
@@ -29,6 +30,15 @@ type ControlHelpFn func(*Cmd, []string)
 // by the returned error.
 type CLIRun func(*Cmd, []string) error
 
+// CLIFinally defines a finalizer fired after the run events. It receives the
+// error returned so far, which is nil on success, and returns the error that
+// is reported instead.
+type CLIFinally func(*Cmd, []string, error) error
+
+// CLIMiddleware wraps the Run event of a Cmd, it is expected to call next
+// to continue the execution.
+type CLIMiddleware func(next CLIRun) CLIRun
+
 // GlobalNormalizeFlagFn defined the signature for the global normalization
 // function that can be used on every pflag set and children commands
 type GlobalNormalizeFlagFn func(f *flag.FlagSet, name string) flag.NormalizedName
@@ -76,11 +86,25 @@ type Cmd struct {
 	// Expected arguments
 	Args PositionalArgs
 
+	// ArgSpecs declares the named positional arguments, in order. The args
+	// are validated and parsed against them after Args, the parsed values
+	// are read with Arg and the typed getters like ArgInt. ArgSpecs are added
+	// to the use line and the help and complete the args when no
+	// ValidArgsFunction is set.
+	ArgSpecs []ArgSpec
+
 	// ArgAliases is List of aliases for ValidArgs.
 	// These are not suggested to the user in the shell completion,
 	// but accepted if entered manually.
 	ArgAliases []string
 
+	// CanonicalArgAliases maps aliases to their canonical ValidArgs, e.g. "po"
+	// to "pods". Like ArgAliases they are not suggested in the shell
+	// completion, but accepted if entered manually and replaced by the
+	// canonical value before the args are validated and given to the
+	// Lifecycle events.
+	CanonicalArgAliases map[string]string
+
 	// Deprecated defines, if this command is deprecated and should print this string when used.
 	Deprecated string
 
@@ -105,12 +129,21 @@ type Cmd struct {
 	// * Run
 	// * PostRun
 	// * GlobalPostRun
-	// All function have the same run signature CLIRun
+	// * Finally
+	// * GlobalFinally
+	// All run function have the same run signature CLIRun, finalizers use
+	// CLIFinally.
 	lifecycle Lifecycle
 
+	// middleware wraps the Run event of this command and its subcommands.
+	middleware []CLIMiddleware
+
 	// args is actual args parsed from flags.
 	args []string
 
+	// argValues holds the parsed values of the ArgSpecs by name.
+	argValues map[string]interface{}
+
 	// Manage all the pflags
 	flags Flags
 
@@ -136,6 +169,21 @@ type Cmd struct {
 	// CompletionOptions is a set of options to control the handling of shell completion
 	CompletionOptions CompletionOptions
 
+	// SignalOptions controls how SIGINT and SIGTERM are handled while the
+	// command executes. Only the options of the root command are used.
+	SignalOptions SignalOptions
+
+	// CrashOptions controls the recovery of panics raised by the Lifecycle
+	// events. Only the options of the root command are used.
+	CrashOptions CrashOptions
+
+	// ExitCodes maps the error returned by the execution to an exit code, see
+	// ExecuteAndExit. Only the codes of the root command are used.
+	ExitCodes ExitCodes
+
+	// interrupted is the number of signals received during execution.
+	interrupted int32
+
 	// isSortedCmds defines, if command slice are sorted or not.
 	isSortedCmds bool
 
@@ -143,6 +191,9 @@ type Cmd struct {
 	calledAs CalledAs
 
 	ctx context.Context
+	// ctxFromRoot reports the ctx was given by the root during execution
+	// rather than set with SetContext.
+	ctxFromRoot bool
 
 	// commands is the list of commands supported by this program.
 	commands []*Cmd
@@ -155,13 +206,20 @@ type Cmd struct {
 	// TraverseChildren parses flags on all parents before executing child command.
 	TraverseChildren bool
 
+	// ChainGlobalRuns runs the GlobalPreRun and GlobalPostRun events of every
+	// ancestor instead of only the nearest one. Only read on the root command.
+	ChainGlobalRuns bool
+
 	// Hidden defines, if this command is hidden and should NOT show up in the list of available commands.
 	Hidden bool
 
-	// SilenceErrors is an option to quiet errors down stream.
+	// SilenceErrors is an option to quiet errors down stream. When set on
+	// this command or any parent, errors are not printed by ExecuteC.
 	SilenceErrors bool
 
-	// SilenceUsage is an option to silence usage when an error occurs.
+	// SilenceUsage is an option to silence usage when an error occurs. When
+	// set on this command or any parent, ExecuteC does not print the usage
+	// after a usage error.
 	SilenceUsage bool
 
 	// DisableFlagParsing disables the flag parsing.
@@ -188,23 +246,31 @@ type Cmd struct {
 // Find the target command given the args and the cmd tree.
 // This should be run on the highest node. Only searches down.
 func (c *Cmd) Find(args []string) (*Cmd, []string, error) {
-	var innerFind func(*Cmd, []string) (*Cmd, []string)
+	var innerFind func(*Cmd, []string) (*Cmd, []string, error)
 
-	innerFind = func(c *Cmd, innerArgs []string) (*Cmd, []string) {
+	innerFind = func(c *Cmd, innerArgs []string) (*Cmd, []string, error) {
 		argsWOflags := stripFlags(innerArgs, c)
 		if len(argsWOflags) == 0 {
-			return c, innerArgs
+			return c, innerArgs, nil
 		}
 		nextSubCmd := argsWOflags[0]
 
-		cmd := c.findNext(nextSubCmd)
+		cmd, err := c.findNext(nextSubCmd)
+		if err != nil {
+			return c, innerArgs, err
+		}
+
 		if cmd != nil {
 			return innerFind(cmd, argsMinusFirstX(innerArgs, nextSubCmd))
 		}
-		return c, innerArgs
+		return c, innerArgs, nil
+	}
+
+	found, a, err := innerFind(c, args)
+	if err != nil {
+		return found, a, err
 	}
 
-	found, a := innerFind(c, args)
 	if found.Args == nil {
 		return found, a, legacyArgs(found, stripFlags(a, found))
 	}
@@ -218,23 +284,196 @@ func (c *Cmd) preRun() {
 	}
 }
 
+// Execute uses the args (os.Args[1:] by default) to find and run the
+// target command in the tree.
 func (c *Cmd) Execute() error {
 	_, err := c.ExecuteC()
 	return err
 }
 
-func (c *Cmd) ExecuteC() (*Cmd, error) {
-	if c.ctx == nil {
-		c.ctx = context.Background()
-	}
+// ExecuteContext is the same as Execute but sets ctx on the root command
+// and every command resolved along the way. Retrieve it with cmd.Context()
+// inside any of the Lifecycle events.
+func (c *Cmd) ExecuteContext(ctx context.Context) error {
+	_, err := c.ExecuteContextC(ctx)
+	return err
+}
 
+// ExecuteContextC is the same as ExecuteC but sets ctx on the root command
+// and every command resolved along the way. Retrieve it with cmd.Context()
+// inside any of the Lifecycle events.
+func (c *Cmd) ExecuteContextC(ctx context.Context) (*Cmd, error) {
+	c.Root().ctx = ctx
+	return c.ExecuteC()
+}
+
+// ExecuteC uses the args (os.Args[1:] by default) to find the target
+// command in the tree, executes it and returns the command that was run
+// along with any error it produced.
+func (c *Cmd) ExecuteC() (cmd *Cmd, err error) {
 	// Regardless of what command execute is called on, run on Root only.
 	if c.HasParent() {
 		return c.Root().ExecuteC()
 	}
 
+	if c.ctx == nil {
+		c.ctx = context.Background()
+	}
+
 	// initialize help at the last point to allow for user overriding.
-	return nil, nil
+	c.InitDefaultHelpCmd()
+	// initialize completion at the last point to allow for user overriding.
+	c.InitDefaultCompletionCmd()
+
+	if c.SignalOptions.Handle {
+		stop := c.notifySignals()
+		defer stop()
+	}
+
+	args := c.args
+	if args == nil {
+		args = os.Args[1:]
+	}
+
+	// initialize the hidden command to be used for shell completion
+	c.initCompleteCmd(args)
+
+	var flags []string
+	if c.TraverseChildren {
+		cmd, flags, err = c.Traverse(args)
+	} else {
+		cmd, flags, err = c.Find(args)
+	}
+
+	if err != nil {
+		// when we resolved part of the path, report on the deepest command
+		if cmd == nil {
+			cmd = c
+		}
+		err = toUsageError(err)
+		cmd.reportError(err)
+		return cmd, err
+	}
+
+	cmd.calledAs.IsCalled = true
+	if cmd.calledAs.Name == "" {
+		cmd.calledAs.Name = cmd.Name()
+	}
+
+	// the root context governs the whole execution, so every command
+	// from the root down to the one being run shares it, unless a context
+	// was set on the command with SetContext. Such a context is still
+	// cancelled on signals when they are handled.
+	for p := cmd; p != c; p = p.parent {
+		if p.ctx == nil || p.ctxFromRoot {
+			p.ctx = c.ctx
+			p.ctxFromRoot = true
+			continue
+		}
+
+		if c.SignalOptions.Handle {
+			restore := p.cancelOnSignal(c.ctx)
+			defer restore()
+		}
+	}
+
+	err = cmd.execute(flags)
+	if errors.Is(err, flag.ErrHelp) {
+		return cmd, cmd.Help()
+	}
+
+	cmd.reportError(err)
+	return cmd, err
+}
+
+// reportError prints err to the error stream followed by the usage when err
+// is a usage error, see IsUsageError. SilenceErrors and SilenceUsage, set on
+// the command or any parent, turn off the error and the usage respectively.
+func (c *Cmd) reportError(err error) {
+	if err == nil {
+		return
+	}
+
+	streams := c.dataStreams()
+	if !c.isErrorSilenced() {
+		streams.PrintErrln("Error:", err.Error())
+	}
+
+	if IsUsageError(err) && !c.isUsageSilenced() {
+		streams.PrintErr(c.UsageString())
+	}
+}
+
+// isErrorSilenced determines if SilenceErrors is set on the command or any
+// parent.
+func (c *Cmd) isErrorSilenced() bool {
+	for p := c; p != nil; p = p.Parent() {
+		if p.SilenceErrors {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isUsageSilenced determines if SilenceUsage is set on the command or any
+// parent.
+func (c *Cmd) isUsageSilenced() bool {
+	for p := c; p != nil; p = p.Parent() {
+		if p.SilenceUsage {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Traverse the command tree to find the command, and parse args for
+// each parent along the way.
+func (c *Cmd) Traverse(args []string) (*Cmd, []string, error) {
+	var flags []string
+	inFlag := false
+
+	for i, arg := range args {
+		switch {
+		// A long flag with a space separated value
+		case strings.HasPrefix(arg, "--") && !strings.Contains(arg, "="):
+			inFlag = !hasNoOptDefVal(arg[2:], c.Flags())
+			flags = append(flags, arg)
+			continue
+		// A short flag with a space separated value
+		case strings.HasPrefix(arg, "-") && !strings.Contains(arg, "=") && len(arg) == 2 && !shortHasNoOptDefVal(arg[1:], c.Flags()):
+			inFlag = true
+			flags = append(flags, arg)
+			continue
+		// The value for a flag
+		case inFlag:
+			inFlag = false
+			flags = append(flags, arg)
+			continue
+		// A flag without a value, or with an `=` separated value
+		case isFlagArg(arg):
+			flags = append(flags, arg)
+			continue
+		}
+
+		cmd, err := c.findNext(arg)
+		if err != nil {
+			return c, args, err
+		}
+
+		if cmd == nil {
+			return c, args, nil
+		}
+
+		if err := c.ParseFlags(flags); err != nil {
+			return nil, args, err
+		}
+
+		return cmd.Traverse(args[i+1:])
+	}
+
+	return c, args, nil
 }
 
 func (c *Cmd) execute(a []string) (err error) {
@@ -242,10 +481,10 @@ func (c *Cmd) execute(a []string) (err error) {
 		return failure.System("can not execute on a Cmd that is nil")
 	}
 
-	streams := c.streams
+	streams := c.dataStreams()
 
 	if len(c.Deprecated) > 0 {
-		streams.Printf("Command %q is deprecated, %s\n", c.Deprecated)
+		streams.Printf("Command %q is deprecated, %s\n", c.Name(), c.Deprecated)
 	}
 
 	// initialize help and version flag at the last point possible to allow
@@ -254,7 +493,7 @@ func (c *Cmd) execute(a []string) (err error) {
 	c.InitDefaultVersionFlag()
 
 	if err = c.ParseFlags(a); err != nil {
-		return c.FlagErrorFn()(c, err)
+		return c.finalize(a, toUsageError(c.FlagErrorFn()(c, err)))
 	}
 
 	// If help is called, regardless of the other flags, return we want help.
@@ -300,21 +539,74 @@ func (c *Cmd) execute(a []string) (err error) {
 		argWoFlags = a
 	}
 
+	// a badly declared spec is not a usage error, report it as is.
+	if err := c.validateArgSpecs(); err != nil {
+		return c.finalize(argWoFlags, err)
+	}
+
+	argWoFlags = c.normalizeArgAliases(argWoFlags)
 	if err := c.ValidateArgs(argWoFlags); err != nil {
-		return err
+		return c.finalize(argWoFlags, toUsageError(err))
 	}
 
-	for p := c; p != nil; p = p.Parent() {
-		if p.lifecycle.GlobalPreRun != nil {
-			if err := p.lifecycle.GlobalPreRun(c, argWoFlags); err != nil {
-				return err
-			}
-			break
+	return c.runLifecycle(argWoFlags)
+}
+
+// runLifecycle fires the run events followed by the finalizers.
+func (c *Cmd) runLifecycle(args []string) error {
+	err := c.recoverPanic(args, func() error { return c.runEvents(args) })
+	if err == nil {
+		err = c.recoverPanic(args, func() error { return c.runGlobalPostRun(args) })
+	} else if c.Root().isInterrupted() {
+		// cleanup must still happen when the user interrupted the command,
+		// the error of the interrupted event is the one that is reported.
+		_ = c.recoverPanic(args, func() error { return c.runGlobalPostRun(args) })
+	}
+
+	return c.finalize(args, err)
+}
+
+// finalize fires the finalizers with err. When the root CrashOptions enable
+// it, a panic raised by a finalizer is recovered and a crash report is
+// written for the PanicError that ends the execution.
+func (c *Cmd) finalize(args []string, err error) error {
+	err = c.recoverPanic(args, func() error { return c.runFinally(args, err) })
+
+	var pe *PanicError
+	if c.Root().CrashOptions.Recover && errors.As(err, &pe) {
+		c.reportCrash(pe)
+	}
+
+	return err
+}
+
+// recoverPanic calls fn, converting a panic it raises into a PanicError when
+// the root CrashOptions enable it.
+func (c *Cmd) recoverPanic(args []string, fn func() error) (err error) {
+	if !c.Root().CrashOptions.Recover {
+		return fn()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = newPanicError(c, args, r)
+		}
+	}()
+
+	return fn()
+}
+
+// runEvents fires the GlobalPreRun events followed by the PreRun, Run and
+// PostRun events of this command, stopping at the first error.
+func (c *Cmd) runEvents(args []string) error {
+	for _, p := range c.globalEventCmds(func(l Lifecycle) bool { return l.GlobalPreRun != nil }) {
+		if err := p.lifecycle.GlobalPreRun(c, args); err != nil {
+			return err
 		}
 	}
 
 	if c.lifecycle.PreRun != nil {
-		if err := c.lifecycle.PreRun(c, argWoFlags); err != nil {
+		if err := c.lifecycle.PreRun(c, args); err != nil {
 			return err
 		}
 	}
@@ -323,30 +615,93 @@ func (c *Cmd) execute(a []string) (err error) {
 		return err
 	}
 
+	if err := c.validateFlagGroups(); err != nil {
+		return err
+	}
+
 	if c.lifecycle.Run != nil {
-		if err := c.lifecycle.Run(c, argWoFlags); err != nil {
+		if err := c.run(args); err != nil {
 			return err
 		}
 	}
 
 	if c.lifecycle.PostRun != nil {
-		if err := c.lifecycle.PostRun(c, argWoFlags); err != nil {
+		if err := c.lifecycle.PostRun(c, args); err != nil {
 			return err
 		}
 	}
 
+	return nil
+}
+
+// run fires the Run event wrapped by the middleware, a panic is converted
+// into an error so the finalizers still get a chance to run.
+func (c *Cmd) run(args []string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = newPanicError(c, args, r)
+		}
+	}()
+
+	run := c.lifecycle.Run
 	for p := c; p != nil; p = p.Parent() {
-		if p.lifecycle.GlobalPostRun != nil {
-			if err := p.lifecycle.GlobalPostRun(c, argWoFlags); err != nil {
-				return err
-			}
-			break
+		for i := len(p.middleware) - 1; i >= 0; i-- {
+			run = p.middleware[i](run)
+		}
+	}
+
+	return run(c, args)
+}
+
+// runGlobalPostRun fires the GlobalPostRun events, stopping at the first error.
+func (c *Cmd) runGlobalPostRun(args []string) error {
+	cmds := c.globalEventCmds(func(l Lifecycle) bool { return l.GlobalPostRun != nil })
+	for i := len(cmds) - 1; i >= 0; i-- {
+		if err := cmds[i].lifecycle.GlobalPostRun(c, args); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
+// runFinally fires the Finally event of this command followed by the
+// GlobalFinally events. Every finalizer is fired, each one receiving the error
+// returned by the previous one.
+func (c *Cmd) runFinally(args []string, err error) error {
+	if c.lifecycle.Finally != nil {
+		err = c.lifecycle.Finally(c, args, err)
+	}
+
+	cmds := c.globalEventCmds(func(l Lifecycle) bool { return l.GlobalFinally != nil })
+	for i := len(cmds) - 1; i >= 0; i-- {
+		err = cmds[i].lifecycle.GlobalFinally(c, args, err)
+	}
+
+	return err
+}
+
+// globalEventCmds collects this command and its parents that define the
+// global event reported by has, ordered from the root down to this command.
+// Unless the root has ChainGlobalRuns set only the nearest one is returned.
+func (c *Cmd) globalEventCmds(has func(Lifecycle) bool) []*Cmd {
+	chain := c.Root().ChainGlobalRuns
+
+	var cmds []*Cmd
+	for p := c; p != nil; p = p.Parent() {
+		if !has(p.lifecycle) {
+			continue
+		}
+
+		cmds = append([]*Cmd{p}, cmds...)
+		if !chain {
+			break
+		}
+	}
+
+	return cmds
+}
+
 // InitDefaultHelpCmd adds default help command to this command.
 // It is called automatically by executing the cmd or by calling help
 // and usage. Ignored if cmd already has help or no subcommands
@@ -356,17 +711,191 @@ func (c *Cmd) InitDefaultHelpCmd() {
 	}
 
 	if c.help.Default == nil {
+		c.help.Default = NewDefaultHelpCmd(c)
+	}
 
+	c.Remove(c.help.Default)
+	c.Add(c.help.Default)
+}
+
+// SetHelpCmd sets the command used as the default help command.
+func (c *Cmd) SetHelpCmd(cmd *Cmd) {
+	c.help.Default = cmd
+}
+
+// SetHelpClosure assigns a user defined closure used to print help.
+func (c *Cmd) SetHelpClosure(fn ControlHelpFn) {
+	c.help.Control = fn
+}
+
+// SetHelpTemplate allows the user to control the help template.
+func (c *Cmd) SetHelpTemplate(s string) {
+	c.help.Template = s
+}
+
+// HelpFunc returns the help closure set on this command or the nearest
+// parent. When none is set, the help template is rendered to the output
+// stream.
+func (c *Cmd) HelpFunc() ControlHelpFn {
+	for p := c; p != nil; p = p.parent {
+		if p.help.Control != nil {
+			return p.help.Control
+		}
+	}
+
+	return func(c *Cmd, a []string) {
+		if err := tpl(c.OutputStream(), c.HelpTemplate(), c); err != nil {
+			c.dataStreams().PrintErrln(err)
+		}
 	}
 }
 
-func (c *Cmd) ValidateArgs(args []string) error {
-	if c.Args == nil {
-		return nil
+// HelpTemplate returns the help template set on this command or the
+// nearest parent, falling back to the default help template.
+func (c *Cmd) HelpTemplate() string {
+	if c.help.Template != "" {
+		return c.help.Template
 	}
 
-	return c.Args(c, args)
+	if c.HasParent() {
+		return c.parent.HelpTemplate()
+	}
+
+	return `{{with (or .Long .Short)}}{{. | trimTrailingWhitespace}}
 
+{{end}}{{if or .IsRunnable .HasSubCommands}}{{.UsageString}}{{end}}`
+}
+
+// Usage prints the usage for the command using the closure returned by
+// UsageFunc.
+func (c *Cmd) Usage() error {
+	return c.UsageFunc()(c)
+}
+
+// UsageFunc returns the usage closure set on this command or the nearest
+// parent. When none is set, the usage template is rendered to the error
+// stream.
+func (c *Cmd) UsageFunc() ControlUsageFn {
+	for p := c; p != nil; p = p.parent {
+		if p.usage.Control != nil {
+			return p.usage.Control
+		}
+	}
+
+	return func(c *Cmd) error {
+		c.mergeGlobalFlags()
+		err := tpl(c.ErrorStream(), c.UsageTemplate(), c)
+		if err != nil {
+			c.dataStreams().PrintErrln(err)
+		}
+		return err
+	}
+}
+
+// UsageString returns the usage string rendered by the usage closure.
+func (c *Cmd) UsageString() string {
+	// Storing normal writers
+	out, errOut := c.streams.out, c.streams.err
+
+	bb := new(bytes.Buffer)
+	c.streams.SetOut(bb)
+	c.streams.SetError(bb)
+
+	CheckErr(c.Usage())
+
+	// Setting things back to normal
+	c.streams.SetOut(out)
+	c.streams.SetError(errOut)
+
+	return bb.String()
+}
+
+// UsageTemplate returns the usage template set on this command or the
+// nearest parent, falling back to the default usage template.
+func (c *Cmd) UsageTemplate() string {
+	if c.usage.Template != "" {
+		return c.usage.Template
+	}
+
+	if c.HasParent() {
+		return c.parent.UsageTemplate()
+	}
+
+	return `Usage:{{if .IsRunnable}}
+  {{.UseLine}}{{end}}{{if .HasAvailableSubCommands}}
+  {{.Path}} [command]{{end}}{{if gt (len .Aliases) 0}}
+
+Aliases:
+  {{.NameAndAliases}}{{end}}{{if .HasExample}}
+
+Examples:
+{{.Example}}{{end}}{{if .HasArgSpecs}}
+
+Arguments:
+{{.ArgSpecsUsages | trimTrailingWhitespace}}{{end}}{{if .HasAvailableSubCommands}}
+
+Available Commands:{{range .Commands}}{{if (or .IsAvailableCommand (eq .Name "help"))}}
+  {{rpad .Name .NamePadding }} {{.Short}}{{end}}{{end}}{{end}}{{if .HasAvailableLocalFlags}}
+
+Flags:
+{{.LocalFlags.FlagUsages | trimTrailingWhitespace}}{{end}}{{if .HasAvailableInheritedFlags}}
+
+Global Flags:
+{{.InheritedFlags.FlagUsages | trimTrailingWhitespace}}{{end}}{{if .HasHelpSubCommands}}
+
+Additional help topics:{{range .Commands}}{{if .IsAdditionalHelpTopicCommand}}
+  {{rpad .Path .PathPadding}} {{.Short}}{{end}}{{end}}{{end}}{{if .HasAvailableSubCommands}}
+
+Use "{{.Path}} [command] --help" for more information about a command.{{end}}
+`
+}
+
+// normalizeArgAliases returns a copy of args where the CanonicalArgAliases are
+// replaced by their canonical value. An arg that is itself a ValidArgs is kept,
+// so is an alias of a value missing from ValidArgs to be reported as is.
+func (c *Cmd) normalizeArgAliases(args []string) []string {
+	if len(c.CanonicalArgAliases) == 0 {
+		return args
+	}
+
+	validArgs := c.validArgNames()
+	normalized := make([]string, len(args))
+	for i, arg := range args {
+		normalized[i] = arg
+		canonical, ok := c.CanonicalArgAliases[arg]
+		if !ok || stringInSlice(arg, validArgs) {
+			continue
+		}
+
+		if len(validArgs) == 0 || stringInSlice(canonical, validArgs) {
+			normalized[i] = canonical
+		}
+	}
+
+	return normalized
+}
+
+// validArgNames returns the ValidArgs without their descriptions.
+func (c *Cmd) validArgNames() []string {
+	// A description is following a tab character.
+	names := make([]string, 0, len(c.ValidArgs))
+	for _, v := range c.ValidArgs {
+		names = append(names, strings.Split(v, "\t")[0])
+	}
+
+	return names
+}
+
+// ValidateArgs validates args with Args and then parses them against the
+// ArgSpecs.
+func (c *Cmd) ValidateArgs(args []string) error {
+	if c.Args != nil {
+		if err := c.Args(c, args); err != nil {
+			return err
+		}
+	}
+
+	return c.parseArgSpecs(args)
 }
 
 // VersionTemplate return version template for the command.
@@ -426,8 +955,10 @@ func (c *Cmd) InitDefaultVersionFlag() {
 // previously set context will be returned. Otherwise, nil is returned.
 //
 // Notice that a call to Execute and ExecuteC will replace a nil context of
-// a command with a context.Background, so a background context will be
-// returned by Context after one of these functions has been called.
+// the root command with a context.Background, and every command resolved
+// during execution without a context set with SetContext is given the root
+// context, so a background context will be returned by Context after one of
+// these functions has been called.
 func (c *Cmd) Context() context.Context {
 	return c.ctx
 }
@@ -437,6 +968,43 @@ func (c *Cmd) Context() context.Context {
 // Command.ExecuteContextC
 func (c *Cmd) SetContext(ctx context.Context) {
 	c.ctx = ctx
+	c.ctxFromRoot = false
+}
+
+// Help prints the help for the command using the closure returned by
+// HelpFunc.
+func (c *Cmd) Help() error {
+	c.HelpFunc()(c, []string{})
+	return nil
+}
+
+// CalledAs returns the command name or alias that was used to invoke
+// this command or an empty string if the command has not been called.
+func (c *Cmd) CalledAs() string {
+	if c.calledAs.IsCalled {
+		return c.calledAs.Name
+	}
+
+	return ""
+}
+
+// SetLifecycle assigns the run events fired during the execution of
+// the command.
+func (c *Cmd) SetLifecycle(l Lifecycle) {
+	c.lifecycle = l
+}
+
+// Lifecycle returns the run events assigned to the command.
+func (c *Cmd) Lifecycle() Lifecycle {
+	return c.lifecycle
+}
+
+// AddMiddleware registers middleware wrapping the Run event of this command
+// and all its subcommands. Middleware of parents wrap the middleware of their
+// children, so the chain runs from the root down to the executed command, and
+// middleware registered on the same command run in the order they were added.
+func (c *Cmd) AddMiddleware(mw ...CLIMiddleware) {
+	c.middleware = append(c.middleware, mw...)
 }
 
 // SetArgs sets arguments for the command. It is set to os.Args[1:] by default,
@@ -445,9 +1013,16 @@ func (c *Cmd) SetArgs(a []string) {
 	c.args = a
 }
 
-// InputStream returns the assign stdin
+// InputStream returns the assign stdin. When not set on this command the
+// stream of the nearest parent is used, falling back to os.Stdin.
 func (c *Cmd) InputStream() io.Reader {
-	return c.streams.In()
+	for p := c; p != nil; p = p.parent {
+		if p.streams.in != nil {
+			return p.streams.in
+		}
+	}
+
+	return os.Stdin
 }
 
 // SetInputStream allows the input stream to be assigned to the command.
@@ -455,9 +1030,16 @@ func (c *Cmd) SetInputStream(in io.Reader) {
 	c.streams.SetIn(in)
 }
 
-// OutputStream returns the assign stdout
+// OutputStream returns the assign stdout. When not set on this command the
+// stream of the nearest parent is used, falling back to os.Stdout.
 func (c *Cmd) OutputStream() io.Writer {
-	return c.streams.Out()
+	for p := c; p != nil; p = p.parent {
+		if p.streams.out != nil {
+			return p.streams.out
+		}
+	}
+
+	return os.Stdout
 }
 
 // SetOutputStream allows the output stream to be assigned to the command.
@@ -465,9 +1047,16 @@ func (c *Cmd) SetOutputStream(out io.Writer) {
 	c.streams.SetOut(out)
 }
 
-// ErrorStream returns the assign stderr
+// ErrorStream returns the assign stderr. When not set on this command the
+// stream of the nearest parent is used, falling back to os.Stderr.
 func (c *Cmd) ErrorStream() io.Writer {
-	return c.streams.Error()
+	for p := c; p != nil; p = p.parent {
+		if p.streams.err != nil {
+			return p.streams.err
+		}
+	}
+
+	return os.Stderr
 }
 
 // SetErrorStream allows the error stream to be assigned to the command.
@@ -475,6 +1064,13 @@ func (c *Cmd) SetErrorStream(e io.Writer) {
 	c.streams.SetError(e)
 }
 
+// dataStreams resolves the in, out and error streams for this command,
+// taking into account the streams set on its parents.
+func (c *Cmd) dataStreams() *DataStreams {
+	ds := NewDataStreams(c.InputStream(), c.OutputStream(), c.ErrorStream())
+	return &ds
+}
+
 // SetUsageClosure assign user defined closure for usage
 func (c *Cmd) SetUsageClosure(fn ControlUsageFn) {
 	c.usage.Control = fn
@@ -495,6 +1091,102 @@ func (c *Cmd) NameAndAliases() string {
 	return strings.Join(append([]string{c.Name()}, c.Aliases...), ",")
 }
 
+// IsAvailableCommand determines if a command is available as a non-help
+// command (this includes all non deprecated/hidden commands).
+func (c *Cmd) IsAvailableCommand() bool {
+	if len(c.Deprecated) != 0 || c.Hidden {
+		return false
+	}
+
+	if c.HasParent() && c.Parent().help.Default == c {
+		return false
+	}
+
+	if c.lifecycle.IsRunnable() || c.HasAvailableSubCommands() {
+		return true
+	}
+
+	return false
+}
+
+// HasAvailableSubCommands determines if a command has available sub
+// commands that need to be shown in the usage/help default template under
+// 'available commands'.
+func (c *Cmd) HasAvailableSubCommands() bool {
+	for _, sub := range c.commands {
+		if sub.IsAvailableCommand() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsRunnable determines if the command can be executed.
+func (c *Cmd) IsRunnable() bool {
+	return c.lifecycle.IsRunnable()
+}
+
+// IsAdditionalHelpTopicCommand determines if a command is an additional
+// help topic command; additional help topic command is determined by the
+// fact that it is NOT runnable/hidden/deprecated, and has no sub commands
+// that are runnable/hidden/deprecated.
+func (c *Cmd) IsAdditionalHelpTopicCommand() bool {
+	// if a command is runnable, deprecated, or hidden it is not a 'help' command
+	if c.IsRunnable() || len(c.Deprecated) != 0 || c.Hidden {
+		return false
+	}
+
+	// if any non-help sub commands are found, the command is not a 'help' command
+	for _, sub := range c.commands {
+		if !sub.IsAdditionalHelpTopicCommand() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// HasHelpSubCommands determines if a command has any available 'help'
+// sub commands that need to be shown in the usage/help default template
+// under 'additional help topics'.
+func (c *Cmd) HasHelpSubCommands() bool {
+	for _, sub := range c.commands {
+		if sub.IsAdditionalHelpTopicCommand() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NamePadding returns padding for the name.
+func (c *Cmd) NamePadding() int {
+	if c.HasParent() {
+		return c.parent.maxLength.Name
+	}
+
+	return 0
+}
+
+// PathPadding returns padding for the command path.
+func (c *Cmd) PathPadding() int {
+	if c.HasParent() {
+		return c.parent.maxLength.Path
+	}
+
+	return 0
+}
+
+// UsagePadding returns padding for the usage.
+func (c *Cmd) UsagePadding() int {
+	if c.HasParent() {
+		return c.parent.maxLength.Use
+	}
+
+	return 0
+}
+
 // HasExample determines if the command has examples
 func (c *Cmd) HasExample() bool {
 	return len(c.Example) > 0
@@ -599,12 +1291,86 @@ func (c *Cmd) GlobalFlags() *flag.FlagSet {
 // LocalSpecificFlags are flags specific to this command which will NOT
 // persist to subcommands.
 func (c *Cmd) LocalSpecificFlags() *flag.FlagSet {
-	return nil
+	global := c.GlobalFlags()
+
+	specific := newFlagSet(c.Name())
+	specific.SetOutput(c.flags.LoadErrorBufferWhenEmpty())
+	c.LocalFlags().VisitAll(func(f *flag.Flag) {
+		if global.Lookup(f.Name) == nil {
+			specific.AddFlag(f)
+		}
+	})
+
+	return specific
 }
 
-// LocalFlags returns the local FlagSet specifically set in the current command.
+// LocalFlags returns the local FlagSet specifically set in the current
+// command, which are its own flags and global flags.
 func (c *Cmd) LocalFlags() *flag.FlagSet {
-	return nil
+	c.mergeGlobalFlags()
+
+	if !c.flags.IsLocal() {
+		c.flags.LoadLocalSet(c.Name())
+	}
+
+	local := c.flags.Local
+	local.SortFlags = c.Flags().SortFlags
+	if c.flags.IsGlobalNormalizeFn() {
+		local.SetNormalizeFunc(c.flags.GlobalNormalizeFn)
+	}
+
+	addToLocal := func(f *flag.Flag) {
+		// Add the flag if it is not a parent global flag, or it shadows one
+		if local.Lookup(f.Name) == nil && f != c.flags.ParentsGlobal.Lookup(f.Name) {
+			local.AddFlag(f)
+		}
+	}
+	c.Flags().VisitAll(addToLocal)
+	c.GlobalFlags().VisitAll(addToLocal)
+
+	return local
+}
+
+// InheritedFlags returns all flags which were inherited from parent commands.
+func (c *Cmd) InheritedFlags() *flag.FlagSet {
+	c.mergeGlobalFlags()
+
+	if !c.flags.IsInherited() {
+		c.flags.LoadInheritedSet(c.Name())
+	}
+
+	inherited := c.flags.Inherited
+	if c.flags.IsGlobalNormalizeFn() {
+		inherited.SetNormalizeFunc(c.flags.GlobalNormalizeFn)
+	}
+
+	local := c.LocalFlags()
+	c.flags.ParentsGlobal.VisitAll(func(f *flag.Flag) {
+		if inherited.Lookup(f.Name) == nil && local.Lookup(f.Name) == nil {
+			inherited.AddFlag(f)
+		}
+	})
+
+	return inherited
+}
+
+// HasAvailableLocalFlags determines if the command has flags specifically
+// declared locally which are not hidden or deprecated.
+func (c *Cmd) HasAvailableLocalFlags() bool {
+	return c.LocalFlags().HasAvailableFlags()
+}
+
+// HasAvailableInheritedFlags determines if the command has flags inherited
+// from its parent commands which are not hidden or deprecated.
+func (c *Cmd) HasAvailableInheritedFlags() bool {
+	return c.InheritedFlags().HasAvailableFlags()
+}
+
+// lookupFlag finds the flag called name among the flags of the command,
+// including the global flags inherited from its parents.
+func (c *Cmd) lookupFlag(name string) *flag.Flag {
+	c.mergeGlobalFlags()
+	return c.Flags().Lookup(name)
 }
 
 func (c *Cmd) FlagErrorFn() ControlFlagErrorFn {
@@ -634,9 +1400,10 @@ func (c *Cmd) ParseFlags(args []string) error {
 	c.Flags().ParseErrorsWhitelist = flag.ParseErrorsWhitelist(c.FParseErrWhitelist)
 
 	err := c.Flags().Parse(args)
-	// Print warnings if they occurred (e.g. deprecated flag messages).
+	// Flush warnings if they occurred (e.g. deprecated flag messages).
 	if errorBuf.Len()-beforeErrorLen > 0 && err == nil {
-		// c.Print(errorBuf.String())
+		c.dataStreams().PrintErr(string(errorBuf.Bytes()[beforeErrorLen:]))
+		errorBuf.Truncate(beforeErrorLen)
 	}
 	return err
 }
@@ -678,6 +1445,11 @@ func (c *Cmd) UseLine() string {
 		line = c.parent.Path() + " " + c.Use
 	}
 
+	// a Use holding more than the name already describes the args.
+	if c.HasArgSpecs() && !strings.Contains(c.Use, " ") {
+		line += " " + c.argSpecsUseLine()
+	}
+
 	if c.DisableFlagsInUseLine {
 		return line
 	}
@@ -701,7 +1473,13 @@ func (c *Cmd) GlobalNormalization() GlobalNormalizeFlagFn {
 
 // SetGlobalNormalization assigns the closure to the command
 func (c *Cmd) SetGlobalNormalization(fn GlobalNormalizeFlagFn) {
+	c.Flags().SetNormalizeFunc(fn)
+	c.GlobalFlags().SetNormalizeFunc(fn)
 	c.flags.GlobalNormalizeFn = fn
+
+	for _, command := range c.commands {
+		command.SetGlobalNormalization(fn)
+	}
 }
 
 func (c *Cmd) markCommandsSorted() {
@@ -736,20 +1514,108 @@ func (c *Cmd) updateMaxLengthFrom(child *Cmd) {
 		c.maxLength.Name = nameLen
 	}
 }
-func (c *Cmd) findNext(next string) *Cmd {
-	matches := make([]*Cmd, 0)
+
+// SuggestionsFor provides suggestions for the typedName, taken from the
+// names of the available subcommands. A subcommand is suggested when its
+// name or one of its aliases is within SuggestionsMinimumDistance of
+// typedName or starts with it, or when typedName is one of its SuggestFor.
+func (c *Cmd) SuggestionsFor(typedName string) []string {
+	distance := c.SuggestionsMinimumDistance
+	if distance <= 0 {
+		distance = 2
+	}
+
+	isSimilar := func(name string) bool {
+		return ld(typedName, name, true) <= distance ||
+			strings.HasPrefix(strings.ToLower(name), strings.ToLower(typedName))
+	}
+
+	var suggestions []string
+	for _, cmd := range c.commands {
+		if !cmd.IsAvailableCommand() {
+			continue
+		}
+
+		suggest := isSimilar(cmd.Name())
+		for _, alias := range cmd.Aliases {
+			suggest = suggest || isSimilar(alias)
+		}
+
+		for _, explicit := range cmd.SuggestFor {
+			suggest = suggest || strings.EqualFold(typedName, explicit)
+		}
+
+		if suggest {
+			suggestions = append(suggestions, cmd.Name())
+		}
+	}
+
+	return suggestions
+}
+
+// findSuggestions returns the suggestions for arg reported with an unknown
+// command, none when suggestions are disabled.
+func (c *Cmd) findSuggestions(arg string) []string {
+	if c.DisableSuggestions {
+		return nil
+	}
+
+	return c.SuggestionsFor(arg)
+}
+
+// findNext returns the subcommand called next. When EnablePrefixMatching
+// is set an unambiguous prefix of a subcommand name or alias also matches,
+// while an ambiguous one is reported as an error.
+func (c *Cmd) findNext(next string) (*Cmd, error) {
+	var matches []*Cmd
 	for _, cmd := range c.commands {
 		if cmd.Name() == next || cmd.HasAlias(next) {
 			cmd.calledAs.Name = next
-			return cmd
+			return cmd, nil
+		}
+
+		if EnablePrefixMatching && !cmd.Hidden && cmd.hasNameOrAliasPrefix(next) {
+			matches = append(matches, cmd)
 		}
 	}
 
 	if len(matches) == 1 {
-		return matches[0]
+		return matches[0], nil
 	}
 
-	return nil
+	if len(matches) > 1 {
+		names := make([]string, 0, len(matches))
+		for _, cmd := range matches {
+			names = append(names, cmd.Name())
+		}
+		sort.Strings(names)
+
+		return nil, fmt.Errorf("ambiguous command %q for %q, could be: %s", next, c.Path(), strings.Join(names, ", "))
+	}
+
+	return nil, nil
+}
+
+// hasNameOrAliasPrefix determines if the name or one of the aliases of the
+// command starts with prefix, recording the one that matched as calledAs.
+func (c *Cmd) hasNameOrAliasPrefix(prefix string) bool {
+	if prefix == "" {
+		return false
+	}
+
+	if strings.HasPrefix(c.Name(), prefix) {
+		c.calledAs.Name = c.Name()
+		return true
+	}
+
+	for _, alias := range c.Aliases {
+		if strings.HasPrefix(alias, prefix) {
+			c.calledAs.Name = alias
+			return true
+		}
+	}
+
+	return false
 }
 
 func (c *Cmd) validateRequiredFlags() error {
@@ -770,7 +1636,7 @@ func (c *Cmd) validateRequiredFlags() error {
 	})
 
 	if len(missing) > 0 {
-		return failure.System(`required flag(s) "%s" not set`, strings.Join(missing, `","`))
+		return &RequiredFlagsError{Flags: missing}
 	}
 
 	return nil
@@ -809,6 +1675,7 @@ MAIN:
 		}
 		commands = append(commands, command)
 	}
+	c.commands = commands
 
 	// recompute all lengths
 	c.resetMaxLengths()
@@ -936,7 +1803,7 @@ type MaxLengths struct {
 }
 
 // Reset reverts all lengths to their default values
-func (ml MaxLengths) Reset() {
+func (ml *MaxLengths) Reset() {
 	ml.Use = 0
 	ml.Path = 0
 	ml.Name = 0
@@ -950,13 +1817,31 @@ func (ml MaxLengths) Reset() {
 // * Run
 // * PostRun
 // * GlobalPostRun
-// All events follow the same function signature.
+// * Finally
+// * GlobalFinally
+// All run events follow the same function signature.
+//
+// GlobalPreRun, GlobalPostRun and GlobalFinally are inherited by subcommands,
+// by default only the nearest one, found walking up from the executed command,
+// is fired. When ChainGlobalRuns is set on the root, the GlobalPreRun of every
+// ancestor is fired from the root down to the executed command and the
+// GlobalPostRun and GlobalFinally of every ancestor from the executed command
+// back up to the root. Every event receives the executed command and the run
+// events stop the chain on error.
+//
+// Finally and GlobalFinally are always fired when the command is executed,
+// even when the flags could not be parsed, the args are invalid, a previous
+// event failed or Run panicked. They receive the error so far and the error
+// they return, possibly nil, is the one reported. Showing the help or the
+// version instead of executing the command does not fire them.
 type Lifecycle struct {
 	GlobalPreRun  CLIRun
 	PreRun        CLIRun
 	Run           CLIRun
 	PostRun       CLIRun
 	GlobalPostRun CLIRun
+	Finally       CLIFinally
+	GlobalFinally CLIFinally
 }
 
 // IsRunnable Determines if a command can be executed.
@@ -981,7 +1866,7 @@ func (f *Flags) ClearParentsGlobal() {
 }
 
 func (f *Flags) IsParentsGlobalFlags() bool {
-	return f.ParentsGlobal == nil
+	return f.ParentsGlobal != nil
 }
 
 func (f *Flags) LoadParentsGlobal(name string) {
@@ -1012,7 +1897,7 @@ func (f *Flags) LoadErrorBuffer() {
 }
 
 func (f *Flags) IsFull() bool {
-	return f.Global != nil
+	return f.Full != nil
 }
 
 func (f *Flags) LoadFullSet(name string) {
@@ -1029,6 +1914,24 @@ func (f *Flags) LoadGlobalSet(name string) {
 	f.Global.SetOutput(f.LoadErrorBufferWhenEmpty())
 }
 
+func (f *Flags) IsLocal() bool {
+	return f.Local != nil
+}
+
+func (f *Flags) LoadLocalSet(name string) {
+	f.Local = newFlagSet(name)
+	f.Local.SetOutput(f.LoadErrorBufferWhenEmpty())
+}
+
+func (f *Flags) IsInherited() bool {
+	return f.Inherited != nil
+}
+
+func (f *Flags) LoadInheritedSet(name string) {
+	f.Inherited = newFlagSet(name)
+	f.Inherited.SetOutput(f.LoadErrorBufferWhenEmpty())
+}
+
 // CalledAs is the name of alias used to call a command
 type CalledAs struct {
 	Name     string
@@ -1045,13 +1948,15 @@ func (s sortByName) Len() int           { return len(s) }
 func (s sortByName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 func (s sortByName) Less(i, j int) bool { return s[i].Name() < s[j].Name() }
 
+// NewDefaultHelpCmd creates the "help [command]" command used by c, which
+// resolves the path given to it from the root and prints its help.
 func NewDefaultHelpCmd(c *Cmd) *Cmd {
-	return &Cmd{
+	cmd := &Cmd{
 		Use:   "help [command]",
 		Short: "Help about any command",
 		Long: `Help provides help for any command in the application.
 Simply type ` + c.Name() + ` help [path to command] for full details`,
-		ValidArgs: func(c *Cmd, args []string, toComplete string) ([]string, ShellCompDirective) {
+		ValidArgsFunction: func(c *Cmd, args []string, toComplete string) ([]string, ShellCompDirective) {
 			var completions []string
 			cmd, _, e := c.Root().Find(args)
 			if e != nil {
@@ -1064,9 +1969,34 @@ Simply type ` + c.Name() + ` help [path to command] for full details`,
 			}
 
 			for _, subCmd := range cmd.Commands() {
+				if !subCmd.IsAvailableCommand() && subCmd != cmd.help.Default {
+					continue
+				}
+
+				if strings.HasPrefix(subCmd.Name(), toComplete) {
+					completions = append(completions, fmt.Sprintf("%s\t%s", subCmd.Name(), subCmd.Short))
+				}
 			}
+
+			return completions, ShellCompDirectiveNoFileComp
 		},
 	}
+
+	cmd.SetLifecycle(Lifecycle{
+		Run: func(c *Cmd, args []string) error {
+			target, _, e := c.Root().Find(args)
+			if target == nil || e != nil {
+				c.dataStreams().Printf("Unknown help topic %#q\n", args)
+				return c.Root().Usage()
+			}
+
+			target.InitDefaultHelpFlag()
+			target.InitDefaultVersionFlag()
+			return target.Help()
+		},
+	})
+
+	return cmd
 }
 
 func stripFlags(args []string, c *Cmd) []string {
@@ -0,0 +1,705 @@
+package fuelcell
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	flag "github.com/spf13/pflag"
+)
+
+// executeC runs root with args, capturing the output and error streams.
+func executeC(root *Cmd, args ...string) (*Cmd, string, string, error) {
+	out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+	root.SetOutputStream(out)
+	root.SetErrorStream(errOut)
+	root.SetArgs(args)
+
+	cmd, err := root.ExecuteC()
+	return cmd, out.String(), errOut.String(), err
+}
+
+// newTestTree builds "app" with a "deploy" (alias "dp") command holding a
+// "status" subcommand and a sibling "delete" command. Every Run records the
+// path of the command and the args it received in got.
+func newTestTree(got *[]string) *Cmd {
+	run := func(c *Cmd, args []string) error {
+		*got = append(*got, c.Path()+" "+strings.Join(args, ","))
+		return nil
+	}
+
+	root := &Cmd{Use: "app"}
+	root.GlobalFlags().Bool("verbose", false, "verbose output")
+
+	deploy := &Cmd{Use: "deploy", Aliases: []string{"dp"}, Short: "deploy the app"}
+	deploy.SetLifecycle(Lifecycle{Run: run})
+	deploy.Flags().String("env", "", "target environment")
+
+	status := &Cmd{Use: "status", Short: "status of the deployment"}
+	status.SetLifecycle(Lifecycle{Run: run})
+
+	del := &Cmd{Use: "delete", Short: "delete the app", Args: NoArgs}
+	del.SetLifecycle(Lifecycle{Run: run})
+
+	deploy.Add(status)
+	root.Add(deploy, del)
+	return root
+}
+
+func TestExecuteC_Dispatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		cmdPath string
+		want    []string
+	}{
+		{"subcommand", []string{"deploy"}, "app deploy", []string{"app deploy "}},
+		{"alias", []string{"dp", "x"}, "app deploy", []string{"app deploy x"}},
+		{"nested", []string{"deploy", "status", "a"}, "app deploy status", []string{"app deploy status a"}},
+		{"global flag before command", []string{"--verbose", "deploy", "status"}, "app deploy status", []string{"app deploy status "}},
+		{"local flag", []string{"deploy", "--env", "prod", "y"}, "app deploy", []string{"app deploy y"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			cmd, _, _, err := executeC(newTestTree(&got), tt.args...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if cmd.Path() != tt.cmdPath {
+				t.Errorf("executed %q, want %q", cmd.Path(), tt.cmdPath)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("runs = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteC_TraverseChildren(t *testing.T) {
+	var got []string
+	root := newTestTree(&got)
+	root.TraverseChildren = true
+	root.Flags().String("region", "", "region")
+
+	cmd, _, _, err := executeC(root, "--region", "eu", "deploy", "status")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cmd.Path() != "app deploy status" {
+		t.Errorf("executed %q, want %q", cmd.Path(), "app deploy status")
+	}
+
+	if region, _ := root.Flags().GetString("region"); region != "eu" {
+		t.Errorf("root --region = %q, want %q", region, "eu")
+	}
+}
+
+func TestExecuteC_UnknownCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"unknown command", []string{"deplyo"}, `unknown command "deplyo" for "app"`},
+		{"args to NoArgs", []string{"delete", "extra"}, `unknown command "extra" for "app delete"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			_, _, _, err := executeC(newTestTree(&got), tt.args...)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+
+			if len(got) != 0 {
+				t.Errorf("runs = %q, want none", got)
+			}
+		})
+	}
+}
+
+func TestExecuteC_LifecycleOrder(t *testing.T) {
+	errRun := errors.New("run failed")
+
+	tests := []struct {
+		name   string
+		chain  bool
+		runErr error
+		want   []string
+	}{
+		{
+			name: "nearest global events",
+			want: []string{
+				"deploy:GlobalPreRun", "status:PreRun",
+				"root:middleware", "deploy:middleware", "status:Run",
+				"status:PostRun", "deploy:GlobalPostRun",
+				"status:Finally <nil>", "deploy:GlobalFinally <nil>",
+			},
+		},
+		{
+			name:  "chained global events",
+			chain: true,
+			want: []string{
+				"root:GlobalPreRun", "deploy:GlobalPreRun", "status:PreRun",
+				"root:middleware", "deploy:middleware", "status:Run",
+				"status:PostRun", "deploy:GlobalPostRun", "root:GlobalPostRun",
+				"status:Finally <nil>", "deploy:GlobalFinally <nil>", "root:GlobalFinally <nil>",
+			},
+		},
+		{
+			name:   "failed run",
+			runErr: errRun,
+			want: []string{
+				"deploy:GlobalPreRun", "status:PreRun",
+				"root:middleware", "deploy:middleware", "status:Run",
+				"status:Finally run failed", "deploy:GlobalFinally run failed",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			record := func(name string) CLIRun {
+				return func(*Cmd, []string) error {
+					got = append(got, name)
+					return nil
+				}
+			}
+			finally := func(name string) CLIFinally {
+				return func(_ *Cmd, _ []string, err error) error {
+					got = append(got, name+" "+errString(err))
+					return err
+				}
+			}
+			middleware := func(name string) CLIMiddleware {
+				return func(next CLIRun) CLIRun {
+					return func(c *Cmd, args []string) error {
+						got = append(got, name)
+						return next(c, args)
+					}
+				}
+			}
+
+			root := &Cmd{Use: "app", ChainGlobalRuns: tt.chain}
+			root.SetLifecycle(Lifecycle{
+				GlobalPreRun:  record("root:GlobalPreRun"),
+				GlobalPostRun: record("root:GlobalPostRun"),
+				GlobalFinally: finally("root:GlobalFinally"),
+			})
+			root.AddMiddleware(middleware("root:middleware"))
+
+			deploy := &Cmd{Use: "deploy"}
+			deploy.SetLifecycle(Lifecycle{
+				GlobalPreRun:  record("deploy:GlobalPreRun"),
+				GlobalPostRun: record("deploy:GlobalPostRun"),
+				GlobalFinally: finally("deploy:GlobalFinally"),
+			})
+			deploy.AddMiddleware(middleware("deploy:middleware"))
+
+			status := &Cmd{Use: "status"}
+			status.SetLifecycle(Lifecycle{
+				PreRun: record("status:PreRun"),
+				Run: func(*Cmd, []string) error {
+					got = append(got, "status:Run")
+					return tt.runErr
+				},
+				PostRun: record("status:PostRun"),
+				Finally: finally("status:Finally"),
+			})
+
+			deploy.Add(status)
+			root.Add(deploy)
+
+			_, _, _, err := executeC(root, "deploy", "status")
+			if !errors.Is(err, tt.runErr) || (tt.runErr == nil && err != nil) {
+				t.Errorf("error = %v, want %v", err, tt.runErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteC_Finally(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		run     CLIRun
+		wantErr bool
+	}{
+		{name: "unknown flag", args: []string{"deploy", "--bogus"}, wantErr: true},
+		{name: "invalid args", args: []string{"delete", "extra"}, wantErr: true},
+		{name: "run error", args: []string{"deploy"}, run: func(*Cmd, []string) error { return errors.New("boom") }, wantErr: true},
+		{name: "run panic", args: []string{"deploy"}, run: func(*Cmd, []string) error { panic("boom") }, wantErr: true},
+		{name: "success", args: []string{"deploy"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			root := newTestTree(&got)
+			root.SilenceErrors = true
+			root.SilenceUsage = true
+			root.SetLifecycle(Lifecycle{
+				GlobalFinally: func(_ *Cmd, _ []string, err error) error {
+					got = append(got, "finally "+errString(err))
+					return nil
+				},
+			})
+
+			if tt.run != nil {
+				deploy, _, _ := root.Find([]string{"deploy"})
+				deploy.SetLifecycle(Lifecycle{Run: tt.run})
+			}
+
+			// GlobalFinally swallows the error.
+			if _, _, _, err := executeC(root, tt.args...); err != nil {
+				t.Errorf("error = %v, want the nil returned by GlobalFinally", err)
+			}
+
+			last := ""
+			if len(got) > 0 {
+				last = got[len(got)-1]
+			}
+
+			if !strings.HasPrefix(last, "finally ") || (last == "finally <nil>") == tt.wantErr {
+				t.Errorf("events = %q, want GlobalFinally with the error: %v", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAddMiddleware_ShortCircuit(t *testing.T) {
+	var got []string
+	root := newTestTree(&got)
+	root.SilenceErrors = true
+
+	errDenied := errors.New("denied")
+	root.AddMiddleware(func(next CLIRun) CLIRun {
+		return func(c *Cmd, args []string) error {
+			if len(args) > 0 && args[0] == "prod" {
+				return errDenied
+			}
+			return next(c, args)
+		}
+	})
+
+	if _, _, _, err := executeC(root, "deploy", "prod"); !errors.Is(err, errDenied) {
+		t.Errorf("error = %v, want %v", err, errDenied)
+	}
+
+	if _, _, _, err := executeC(root, "deploy", "staging"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if want := []string{"app deploy staging"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("runs = %q, want %q", got, want)
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return "<nil>"
+	}
+
+	return err.Error()
+}
+
+type ctxKey struct{}
+
+func TestExecuteContext(t *testing.T) {
+	tests := []struct {
+		name     string
+		execOn   string
+		ownCtx   bool
+		wantVal  string
+		canceled bool
+	}{
+		{name: "on the root", execOn: "root", wantVal: "root"},
+		{name: "on the child", execOn: "child", wantVal: "root"},
+		{name: "child with its own context", execOn: "root", ownCtx: true, wantVal: "own"},
+		{name: "canceled", execOn: "root", wantVal: "root", canceled: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got context.Context
+			root := &Cmd{Use: "app"}
+			child := &Cmd{Use: "child"}
+			child.SetLifecycle(Lifecycle{Run: func(c *Cmd, _ []string) error {
+				got = c.Context()
+				return nil
+			}})
+			root.Add(child)
+			root.SetArgs([]string{"child"})
+
+			if tt.ownCtx {
+				child.SetContext(context.WithValue(context.Background(), ctxKey{}, "own"))
+			}
+
+			ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "root"))
+			defer cancel()
+			if tt.canceled {
+				cancel()
+			}
+
+			exec := root
+			if tt.execOn == "child" {
+				exec = child
+			}
+
+			if err := exec.ExecuteContext(ctx); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if v, _ := got.Value(ctxKey{}).(string); v != tt.wantVal {
+				t.Errorf("context value = %q, want %q", v, tt.wantVal)
+			}
+
+			if canceled := got.Err() != nil; canceled != tt.canceled {
+				t.Errorf("context canceled = %v, want %v", canceled, tt.canceled)
+			}
+		})
+	}
+}
+
+func TestExecuteC_Help(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"help command", []string{"help"}, []string{"Usage:\n  app "}},
+		{"help topic", []string{"help", "deploy"}, []string{"deploy the app", "app deploy [flags]"}},
+		{"help flag", []string{"deploy", "status", "--help"}, []string{"status of the deployment", "app deploy status [flags]"}},
+		{"unknown topic", []string{"help", "nope"}, []string{"Unknown help topic", "Usage:"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			_, out, errOut, err := executeC(newTestTree(&got), tt.args...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// the usage of an unknown topic goes to the error stream.
+			out += errOut
+			for _, w := range tt.want {
+				if !strings.Contains(out, w) {
+					t.Errorf("output does not contain %q:\n%s", w, out)
+				}
+			}
+
+			if len(got) != 0 {
+				t.Errorf("help ran %q", got)
+			}
+		})
+	}
+}
+
+func TestHelpFunc_Inherited(t *testing.T) {
+	var got []string
+	root := newTestTree(&got)
+
+	var helped []string
+	root.SetHelpClosure(func(c *Cmd, args []string) {
+		helped = append(helped, c.Path())
+	})
+
+	for _, args := range [][]string{{"help", "deploy", "status"}, {"deploy", "--help"}} {
+		if _, _, _, err := executeC(root, args...); err != nil {
+			t.Fatalf("unexpected error for %q: %v", args, err)
+		}
+	}
+
+	want := []string{"app deploy status", "app deploy"}
+	if !reflect.DeepEqual(helped, want) {
+		t.Errorf("help shown for %q, want %q", helped, want)
+	}
+}
+
+func TestUsageTemplate(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"root", []string{"--help"}, []string{`app \[command\]`, `Available Commands:`, `deploy +deploy the app`, `--verbose +verbose output`}},
+		{"subcommand", []string{"help", "deploy"}, []string{`Aliases:\n  deploy, ?dp`, `Available Commands:`, `status +status of the deployment`, `--env string`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			_, out, _, err := executeC(newTestTree(&got), tt.args...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, w := range tt.want {
+				if !regexp.MustCompile(w).MatchString(out) {
+					t.Errorf("output does not match %q:\n%s", w, out)
+				}
+			}
+		})
+	}
+}
+
+func TestUsageTemplate_Inherited(t *testing.T) {
+	var got []string
+	root := newTestTree(&got)
+	root.SetUsageTemplate("usage of {{.Path}}\n")
+
+	status, _, err := root.Find([]string{"deploy", "status"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if usage := status.UsageString(); usage != "usage of app deploy status\n" {
+		t.Errorf("usage = %q, want the template of the root", usage)
+	}
+}
+
+func TestSuggestions(t *testing.T) {
+	tests := []struct {
+		name    string
+		typed   string
+		disable bool
+		want    []string
+	}{
+		{"typo", "deplyo", false, []string{"deploy"}},
+		{"prefix", "delet", false, []string{"delete"}},
+		{"suggest for", "ship", false, []string{"deploy"}},
+		{"too far", "status", false, nil},
+		{"disabled", "deplyo", true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			root := newTestTree(&got)
+			root.DisableSuggestions = tt.disable
+			deploy, _, _ := root.Find([]string{"deploy"})
+			deploy.SuggestFor = []string{"ship"}
+
+			_, _, _, err := executeC(root, tt.typed)
+			if err == nil {
+				t.Fatal("expected an unknown command error")
+			}
+
+			if tt.want == nil {
+				if strings.Contains(err.Error(), "Did you mean this?") {
+					t.Errorf("error %q has suggestions, want none", err)
+				}
+				return
+			}
+
+			if !reflect.DeepEqual(root.SuggestionsFor(tt.typed), tt.want) {
+				t.Errorf("SuggestionsFor(%q) = %q, want %q", tt.typed, root.SuggestionsFor(tt.typed), tt.want)
+			}
+
+			for _, w := range append([]string{"Did you mean this?"}, tt.want...) {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("error %q does not contain %q", err, w)
+				}
+			}
+		})
+	}
+}
+
+func TestExecuteC_PrefixMatching(t *testing.T) {
+	defer func(enabled bool) { EnablePrefixMatching = enabled }(EnablePrefixMatching)
+	EnablePrefixMatching = true
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr string
+	}{
+		{"unique prefix", []string{"dep"}, []string{"app deploy "}, ""},
+		{"unique prefix of a subcommand", []string{"deploy", "stat"}, []string{"app deploy status "}, ""},
+		{"ambiguous single letter", []string{"d"}, nil, "ambiguous command"},
+		{"ambiguous prefix", []string{"de"}, nil, `ambiguous command "de" for "app", could be: delete, deploy`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			_, _, _, err := executeC(newTestTree(&got), tt.args...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("runs = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalAndInheritedFlags(t *testing.T) {
+	tests := []struct {
+		name          string
+		path          []string
+		local         []string
+		localSpecific []string
+		inherited     []string
+	}{
+		{"root", nil, []string{"verbose"}, nil, nil},
+		{"child", []string{"deploy"}, []string{"env"}, []string{"env"}, []string{"verbose"}},
+		{"grandchild", []string{"deploy", "status"}, nil, nil, []string{"verbose"}},
+	}
+
+	names := func(flags *flag.FlagSet) []string {
+		var names []string
+		flags.VisitAll(func(f *flag.Flag) { names = append(names, f.Name) })
+		return names
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			root := newTestTree(&got)
+			cmd, _, err := root.Find(tt.path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if local := names(cmd.LocalFlags()); !reflect.DeepEqual(local, tt.local) {
+				t.Errorf("LocalFlags = %q, want %q", local, tt.local)
+			}
+
+			if specific := names(cmd.LocalSpecificFlags()); !reflect.DeepEqual(specific, tt.localSpecific) {
+				t.Errorf("LocalSpecificFlags = %q, want %q", specific, tt.localSpecific)
+			}
+
+			if inherited := names(cmd.InheritedFlags()); !reflect.DeepEqual(inherited, tt.inherited) {
+				t.Errorf("InheritedFlags = %q, want %q", inherited, tt.inherited)
+			}
+
+			if cmd.HasAvailableLocalFlags() != (len(tt.local) > 0) {
+				t.Errorf("HasAvailableLocalFlags = %v, want %v", cmd.HasAvailableLocalFlags(), len(tt.local) > 0)
+			}
+
+			if cmd.HasAvailableInheritedFlags() != (len(tt.inherited) > 0) {
+				t.Errorf("HasAvailableInheritedFlags = %v, want %v", cmd.HasAvailableInheritedFlags(), len(tt.inherited) > 0)
+			}
+		})
+	}
+}
+
+func TestUsageTemplate_FlagSections(t *testing.T) {
+	var got []string
+	_, out, _, err := executeC(newTestTree(&got), "deploy", "--help")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	local := strings.Index(out, "\nFlags:\n")
+	global := strings.Index(out, "\nGlobal Flags:\n")
+	if local < 0 || global < local {
+		t.Fatalf("the help does not list the local flags before the global flags:\n%s", out)
+	}
+
+	if !strings.Contains(out[local:global], "--env string") || strings.Contains(out[local:global], "--verbose") {
+		t.Errorf("the local flags section is wrong:\n%s", out[local:global])
+	}
+
+	if !strings.Contains(out[global:], "--verbose") {
+		t.Errorf("the global flags section is wrong:\n%s", out[global:])
+	}
+}
+
+func TestExecuteC_Silence(t *testing.T) {
+	errFailed := errors.New("deploy failed")
+
+	tests := []struct {
+		name          string
+		args          []string
+		silenceErrors string
+		silenceUsage  string
+		wantError     bool
+		wantUsage     bool
+	}{
+		{name: "usage error", args: []string{"deplyo"}, wantError: true, wantUsage: true},
+		{name: "run error", args: []string{"deploy", "fail"}, wantError: true},
+		{name: "errors silenced", args: []string{"deplyo"}, silenceErrors: "app", wantUsage: true},
+		{name: "usage silenced", args: []string{"deplyo"}, silenceUsage: "app", wantError: true},
+		{name: "usage silenced on a parent", args: []string{"deploy", "status", "--bogus"}, silenceUsage: "deploy", wantError: true},
+		{name: "errors silenced on a parent", args: []string{"deploy", "status", "--bogus"}, silenceErrors: "deploy", wantUsage: true},
+		{name: "errors silenced on another command", args: []string{"delete", "--bogus"}, silenceErrors: "deploy", wantError: true, wantUsage: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			root := newTestTree(&got)
+			deploy, _, _ := root.Find([]string{"deploy"})
+			deploy.SetLifecycle(Lifecycle{Run: func(*Cmd, []string) error {
+				return errFailed
+			}})
+
+			cmds := map[string]*Cmd{"app": root, "deploy": deploy}
+			if c := cmds[tt.silenceErrors]; c != nil {
+				c.SilenceErrors = true
+			}
+			if c := cmds[tt.silenceUsage]; c != nil {
+				c.SilenceUsage = true
+			}
+
+			_, _, errOut, err := executeC(root, tt.args...)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+
+			if printed := strings.Contains(errOut, "Error: "+err.Error()); printed != tt.wantError {
+				t.Errorf("error printed = %v, want %v:\n%s", printed, tt.wantError, errOut)
+			}
+
+			if printed := strings.Contains(errOut, "Usage:"); printed != tt.wantUsage {
+				t.Errorf("usage printed = %v, want %v:\n%s", printed, tt.wantUsage, errOut)
+			}
+		})
+	}
+}
+
+func TestExecuteC_FlagWarnings(t *testing.T) {
+	var got []string
+	root := newTestTree(&got)
+	deploy, _, _ := root.Find([]string{"deploy"})
+	deploy.Flags().String("target", "", "target environment")
+	if err := deploy.Flags().MarkDeprecated("target", "use --env instead"); err != nil {
+		t.Fatalf("MarkDeprecated failed: %v", err)
+	}
+
+	_, out, errOut, err := executeC(root, "deploy", "--target", "prod")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := "Flag --target has been deprecated, use --env instead"; !strings.Contains(errOut, want) || strings.Contains(out, want) {
+		t.Errorf("error stream = %q, want %q", errOut, want)
+	}
+}
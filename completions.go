@@ -1,5 +1,16 @@
 package fuelcell
 
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/rsb/failure"
+	pflag "github.com/spf13/pflag"
+)
+
 const (
 	// ShellCompRequestCmd is the name of the hidden command that is used to request
 	// completion results from the program.  It is used by the shell completion scripts.
@@ -13,6 +24,13 @@ const (
 // can be instructed to have once completions have been provided.
 type ShellCompDirective int
 
+var (
+	// flagCompletionFunctions holds the completion closures registered for
+	// flags, which are shared between a command and its subcommands.
+	flagCompletionFunctions = map[*pflag.Flag]func(cmd *Cmd, args []string, toComplete string) ([]string, ShellCompDirective){}
+	flagCompletionMutex     = &sync.RWMutex{}
+)
+
 type flagCompError struct {
 	subCommand string
 	flagName   string
@@ -81,3 +99,677 @@ type CompletionOptions struct {
 	// HiddenDefaultCmd makes the default 'completion' command hidden
 	HiddenDefaultCmd bool
 }
+
+// RegisterFlagCompletionFunc should be called to register a function to
+// provide completion for the value of a flag. The flag can be declared on
+// the command or be a global flag of one of its parents.
+func (c *Cmd) RegisterFlagCompletionFunc(flagName string, fn func(cmd *Cmd, args []string, toComplete string) ([]string, ShellCompDirective)) error {
+	flag := c.lookupFlag(flagName)
+	if flag == nil {
+		return failure.NotFound("RegisterFlagCompletionFunc: flag %q does not exist", flagName)
+	}
+
+	flagCompletionMutex.Lock()
+	defer flagCompletionMutex.Unlock()
+
+	if _, exists := flagCompletionFunctions[flag]; exists {
+		return failure.InvalidParam("RegisterFlagCompletionFunc: flag %q already registered", flagName)
+	}
+	flagCompletionFunctions[flag] = fn
+
+	return nil
+}
+
+// initCompleteCmd adds the hidden __complete command to c, but only when
+// it is the command being called. This reduces the side effects of the
+// command, for example a root command without subcommands would suddenly
+// have one.
+func (c *Cmd) initCompleteCmd(args []string) {
+	completeCmd := &Cmd{
+		Use:                   fmt.Sprintf("%s [command-line]", ShellCompRequestCmd),
+		Aliases:               []string{ShellCompNoDescRequestCmd},
+		DisableFlagsInUseLine: true,
+		Hidden:                true,
+		DisableFlagParsing:    true,
+		Args:                  MinimumNArgs(1),
+		Short:                 "Request shell completion choices for the specified command-line",
+		Long: fmt.Sprintf("%[2]s is a special command that is used by the shell completion logic\n%[1]s",
+			"to request completion choices for the specified command-line.", ShellCompRequestCmd),
+	}
+
+	completeCmd.SetLifecycle(Lifecycle{
+		Run: func(cmd *Cmd, args []string) error {
+			finalCmd, completions, directive, err := cmd.getCompletions(args)
+			if err != nil {
+				CompErrorln(err.Error())
+				// Keep going for multiple reasons:
+				// 1- There could be some valid completions even though there was an error
+				// 2- Even without completions, we need to print the directive
+			}
+
+			out := finalCmd.OutputStream()
+			noDescriptions := cmd.CalledAs() == ShellCompNoDescRequestCmd
+			for _, comp := range completions {
+				if noDescriptions {
+					// Remove any description that may be included following a tab character.
+					comp = strings.Split(comp, "\t")[0]
+				}
+
+				// Make sure we only write the first line to the output. This is
+				// needed if a description contains a linebreak, otherwise the
+				// shell scripts would interpret the other lines as completions.
+				comp = strings.Split(comp, "\n")[0]
+
+				// Trimming gets rid of a trailing tab when there is no
+				// description following it.
+				comp = strings.TrimSpace(comp)
+
+				// Print each possible completion to stdout for the completion script to consume.
+				_, _ = fmt.Fprintln(out, comp)
+			}
+
+			// As the last printout, print the completion directive for the completion script to parse.
+			// The directive integer must be that last character following a single colon (:).
+			// The completion script expects :<directive>
+			_, _ = fmt.Fprintf(out, ":%d\n", directive)
+
+			// Print some helpful info to stderr for the user to understand.
+			// Output from stderr must be ignored by the completion script.
+			_, _ = fmt.Fprintf(finalCmd.ErrorStream(), "Completion ended with directive: %s\n", directive.string())
+			return nil
+		},
+	})
+
+	c.Add(completeCmd)
+	subCmd, _, err := c.Find(args)
+	if err != nil || subCmd.Name() != ShellCompRequestCmd {
+		c.Remove(completeCmd)
+	}
+}
+
+// getCompletions resolves the command targeted by the partial command line
+// in args, where the last element is the word being completed, and returns
+// the completions for it along with the directive for the shell.
+func (c *Cmd) getCompletions(args []string) (*Cmd, []string, ShellCompDirective, error) {
+	// The last argument, which is not completely typed by the user,
+	// should not be part of the list of arguments
+	toComplete := args[len(args)-1]
+	trimmedArgs := args[:len(args)-1]
+
+	var finalCmd *Cmd
+	var finalArgs []string
+	var err error
+	// Find the real command for which completion must be performed
+	// check if we need to traverse here to parse local flags on parent commands
+	root := c.Root()
+	if root.TraverseChildren {
+		finalCmd, finalArgs, err = root.Traverse(trimmedArgs)
+	} else {
+		// A root command without Args and without subcommands accepts any
+		// argument, but because __complete was added, Find -> legacyArgs
+		// would report them as unknown commands. Removing __complete gets
+		// the root back to having no subcommands.
+		if len(root.Commands()) == 1 {
+			root.Remove(c)
+		}
+
+		finalCmd, finalArgs, err = root.Find(trimmedArgs)
+	}
+
+	if err != nil {
+		// Unable to find the real command. E.g., <program> someInvalidCmd <TAB>
+		return c, []string{}, ShellCompDirectiveDefault, fmt.Errorf("unable to find a command for arguments: %v", trimmedArgs)
+	}
+	finalCmd.ctx = c.ctx
+
+	// the help and version flags are only added when executing, they are
+	// needed here so they can be completed and parsed.
+	finalCmd.InitDefaultHelpFlag()
+	finalCmd.InitDefaultVersionFlag()
+
+	// Check if we are doing flag value completion before parsing the flags.
+	// This is important because if we are completing a flag value, we need to also
+	// remove the flag name argument from the list of finalArgs or else the parsing
+	// could fail due to an invalid value (incomplete) for the flag.
+	flag, finalArgs, toComplete, flagErr := checkIfFlagCompletion(finalCmd, finalArgs, toComplete)
+
+	// Check if interspersed is false or -- was set on a previous arg.
+	// This works by counting the arguments. Normally -- is not counted as arg but
+	// if -- was already set or interspersed is false and there is already one arg then
+	// the extra added -- is counted as arg.
+	flagCompletion := true
+	_ = finalCmd.ParseFlags(append(finalArgs, "--"))
+	newArgCount := finalCmd.Flags().NArg()
+
+	// Parse the flags early so we can check if required flags are set
+	if err = finalCmd.ParseFlags(finalArgs); err != nil {
+		return finalCmd, []string{}, ShellCompDirectiveDefault, fmt.Errorf("error while parsing flags from args %v: %s", finalArgs, err.Error())
+	}
+
+	realArgCount := finalCmd.Flags().NArg()
+	if newArgCount > realArgCount {
+		// don't do flag completion (see above)
+		flagCompletion = false
+	}
+
+	// Error while attempting to parse flags
+	if flagErr != nil {
+		// If error type is flagCompError and we don't want flagCompletion we should ignore the error
+		if _, ok := flagErr.(*flagCompError); !(ok && !flagCompletion) {
+			return finalCmd, []string{}, ShellCompDirectiveDefault, flagErr
+		}
+	}
+
+	// Look for the --help or --version flags. If they are present,
+	// there should be no further completions.
+	if helpOrVersionFlagPresent(finalCmd) {
+		return finalCmd, []string{}, ShellCompDirectiveNoFileComp, nil
+	}
+
+	// We only remove the flags from the arguments if DisableFlagParsing is not set.
+	// This is important for commands which have requested to do their own flag completion.
+	if !finalCmd.DisableFlagParsing {
+		finalArgs = finalCmd.Flags().Args()
+	}
+
+	if flag != nil && flagCompletion {
+		// Check if we are completing a flag value subject to annotations
+		if validExts, present := flag.Annotations[BashCompFilenameExt]; present {
+			if len(validExts) != 0 {
+				// File completion filtered by extensions
+				return finalCmd, validExts, ShellCompDirectiveFilterFileExt, nil
+			}
+			// The annotation requests simple file completion, which is the
+			// default behavior anyway, so the annotation is ignored.
+		}
+
+		if subDir, present := flag.Annotations[BashCompSubdirsInDir]; present {
+			if len(subDir) == 1 {
+				// Directory completion from within a directory
+				return finalCmd, subDir, ShellCompDirectiveFilterDirs, nil
+			}
+			// Directory completion
+			return finalCmd, []string{}, ShellCompDirectiveFilterDirs, nil
+		}
+	}
+
+	var completions []string
+	var directive ShellCompDirective
+
+	// Enforce flag groups before doing flag completions
+	finalCmd.enforceFlagGroupsForCompletion()
+
+	// Note that we want to perform flag name completion even if finalCmd.DisableFlagParsing==true;
+	// doing this allows for completion of global flag names even for commands that disable flag parsing.
+	//
+	// When doing completion of a flag name, as soon as an argument starts with
+	// a '-' we know it is a flag. We cannot use isFlagArg() here as it requires
+	// the flag name to be complete
+	if flag == nil && len(toComplete) > 0 && toComplete[0] == '-' && !strings.Contains(toComplete, "=") && flagCompletion {
+		// First check for required flags
+		completions = completeRequireFlags(finalCmd, toComplete)
+
+		// If we have not found any required flags, only then can we show regular flags
+		if len(completions) == 0 {
+			doCompleteFlags := func(f *pflag.Flag) {
+				// If the flag is not already present, or if it can be specified
+				// multiple times (Array or Slice) we suggest it as a completion
+				if !f.Changed ||
+					strings.Contains(f.Value.Type(), "Slice") ||
+					strings.Contains(f.Value.Type(), "Array") {
+					completions = append(completions, getFlagNameCompletions(f, toComplete)...)
+				}
+			}
+
+			// We cannot use finalCmd.Flags() because we may not have called ParseFlags() for commands
+			// that have set DisableFlagParsing; it is ParseFlags() that merges the inherited and
+			// local flags.
+			finalCmd.InheritedFlags().VisitAll(doCompleteFlags)
+			finalCmd.LocalFlags().VisitAll(doCompleteFlags)
+		}
+
+		directive = ShellCompDirectiveNoFileComp
+		if len(completions) == 1 && strings.HasSuffix(completions[0], "=") {
+			// If there is a single completion, the shell usually adds a space
+			// after the completion. We don't want that if the flag ends with an =
+			directive = ShellCompDirectiveNoSpace
+		}
+
+		if !finalCmd.DisableFlagParsing {
+			// If DisableFlagParsing==false, we have completed the flags as known by fuelcell;
+			// we can return what we found.
+			// If DisableFlagParsing==true, fuelcell may not be aware of all flags, so we
+			// let the logic continue to see if ValidArgsFunction needs to be called.
+			return finalCmd, completions, directive, nil
+		}
+	} else {
+		directive = ShellCompDirectiveDefault
+		if flag == nil {
+			foundLocalSpecificFlag := false
+			// If TraverseChildren is true on the root command we don't check for
+			// local flags because we can use a local flag on a parent command
+			if !root.TraverseChildren {
+				// Check if there are any local specific flags on the command-line
+				localSpecificFlags := finalCmd.LocalSpecificFlags()
+				finalCmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+					if localSpecificFlags.Lookup(f.Name) != nil && f.Changed {
+						foundLocalSpecificFlag = true
+					}
+				})
+			}
+
+			// Complete subcommand names, including the help command
+			if len(finalArgs) == 0 && !foundLocalSpecificFlag {
+				// We only complete sub-commands if:
+				// - there are no arguments on the command-line and
+				// - there are no local specific flags on the command-line or TraverseChildren is true
+				for _, subCmd := range finalCmd.Commands() {
+					if subCmd.IsAvailableCommand() || subCmd == finalCmd.help.Default {
+						if strings.HasPrefix(subCmd.Name(), toComplete) {
+							completions = append(completions, fmt.Sprintf("%s\t%s", subCmd.Name(), subCmd.Short))
+						}
+						directive = ShellCompDirectiveNoFileComp
+					}
+				}
+			}
+
+			// Complete required flags even without the '-' prefix
+			completions = append(completions, completeRequireFlags(finalCmd, toComplete)...)
+
+			// Always complete ValidArgs, even if we are completing a subcommand name.
+			// This is for commands that have both subcommands and ValidArgs.
+			// ValidArgs are only for the first argument
+			if len(finalCmd.ValidArgs) > 0 && len(finalArgs) == 0 {
+				for _, validArg := range finalCmd.ValidArgs {
+					if strings.HasPrefix(validArg, toComplete) {
+						completions = append(completions, validArg)
+					}
+				}
+
+				if len(completions) > 0 {
+					directive = ShellCompDirectiveNoFileComp
+				}
+			}
+		}
+	}
+
+	// Find the completion function for the flag or command
+	var completionFn func(cmd *Cmd, args []string, toComplete string) ([]string, ShellCompDirective)
+	if flag != nil && flagCompletion {
+		flagCompletionMutex.RLock()
+		completionFn = flagCompletionFunctions[flag]
+		flagCompletionMutex.RUnlock()
+	} else {
+		completionFn = finalCmd.ValidArgsFunction
+		if completionFn == nil && finalCmd.HasArgSpecs() {
+			completionFn = completeArgSpecs
+		}
+	}
+
+	if completionFn != nil {
+		// Go custom completion defined for this flag or command.
+		var comps []string
+		comps, directive = completionFn(finalCmd, finalArgs, toComplete)
+		completions = append(completions, comps...)
+	}
+
+	return finalCmd, completions, directive, nil
+}
+
+func helpOrVersionFlagPresent(cmd *Cmd) bool {
+	if versionFlag := cmd.Flags().Lookup("version"); versionFlag != nil && versionFlag.Changed {
+		return true
+	}
+
+	if helpFlag := cmd.Flags().Lookup("help"); helpFlag != nil && helpFlag.Changed {
+		return true
+	}
+
+	return false
+}
+
+func getFlagNameCompletions(flag *pflag.Flag, toComplete string) []string {
+	if nonCompletableFlag(flag) {
+		return []string{}
+	}
+
+	var completions []string
+	// Only the --flag form is suggested, never --flag=. Boolean and short
+	// flags don't have the = form, so it is never shown to keep the list
+	// of suggestions short. The = form still works.
+	flagName := "--" + flag.Name
+	if strings.HasPrefix(flagName, toComplete) {
+		completions = append(completions, fmt.Sprintf("%s\t%s", flagName, flag.Usage))
+	}
+
+	flagName = "-" + flag.Shorthand
+	if len(flag.Shorthand) > 0 && strings.HasPrefix(flagName, toComplete) {
+		completions = append(completions, fmt.Sprintf("%s\t%s", flagName, flag.Usage))
+	}
+
+	return completions
+}
+
+func completeRequireFlags(finalCmd *Cmd, toComplete string) []string {
+	var completions []string
+
+	doCompleteRequiredFlags := func(flag *pflag.Flag) {
+		if _, present := flag.Annotations[BashCompOneRequiredFlag]; present {
+			if !flag.Changed {
+				// If the flag is not already present, we suggest it as a completion
+				completions = append(completions, getFlagNameCompletions(flag, toComplete)...)
+			}
+		}
+	}
+
+	finalCmd.InheritedFlags().VisitAll(doCompleteRequiredFlags)
+	finalCmd.LocalFlags().VisitAll(doCompleteRequiredFlags)
+
+	return completions
+}
+
+func checkIfFlagCompletion(finalCmd *Cmd, args []string, lastArg string) (*pflag.Flag, []string, string, error) {
+	if finalCmd.DisableFlagParsing {
+		// We only do flag completion if we are allowed to parse flags
+		// This is important for commands which have requested to do their own flag completion.
+		return nil, args, lastArg, nil
+	}
+
+	var flagName string
+	trimmedArgs := args
+	flagWithEqual := false
+	orgLastArg := lastArg
+
+	// When doing completion of a flag name, as soon as an argument starts with
+	// a '-' we know it is a flag. We cannot use isFlagArg() here as that function
+	// requires the flag name to be complete
+	if len(lastArg) > 0 && lastArg[0] == '-' {
+		index := strings.Index(lastArg, "=")
+		if index < 0 {
+			// Normal flag completion
+			return nil, args, lastArg, nil
+		}
+
+		// Flag with an =
+		if strings.HasPrefix(lastArg[:index], "--") {
+			// Flag has full name
+			flagName = lastArg[2:index]
+		} else {
+			// Flag is shorthand, we have to get the last shorthand flag
+			// name e.g. `-asd` => d to provide the correct completion
+			flagName = lastArg[index-1 : index]
+		}
+		lastArg = lastArg[index+1:]
+		flagWithEqual = true
+	}
+
+	if len(flagName) == 0 && len(args) > 0 {
+		prevArg := args[len(args)-1]
+		// Only consider the case where the flag does not contain an =.
+		// If the flag contains an = it means it has already been fully processed,
+		// so we don't need to deal with it here.
+		if isFlagArg(prevArg) && !strings.Contains(prevArg, "=") {
+			if strings.HasPrefix(prevArg, "--") {
+				// Flag has full name
+				flagName = prevArg[2:]
+			} else {
+				// Flag is shorthand, we have to get the last shorthand flag
+				// name e.g. `-asd` => d to provide the correct completion
+				flagName = prevArg[len(prevArg)-1:]
+			}
+			// Remove the uncompleted flag or else there could be an error created
+			// for an invalid value for that flag
+			trimmedArgs = args[:len(args)-1]
+		}
+	}
+
+	if len(flagName) == 0 {
+		// Not doing flag completion
+		return nil, trimmedArgs, lastArg, nil
+	}
+
+	flag := findFlag(finalCmd, flagName)
+	if flag == nil {
+		// Flag not supported by this command, the interspersed option might be set so return the original args
+		return nil, args, orgLastArg, &flagCompError{subCommand: finalCmd.Name(), flagName: flagName}
+	}
+
+	if !flagWithEqual && len(flag.NoOptDefVal) != 0 {
+		// We had assumed dealing with a two-word flag but the flag is a boolean flag.
+		// In that case, there is no value following it, so we are not really doing flag completion.
+		// Reset everything to do noun completion.
+		trimmedArgs = args
+		flag = nil
+	}
+
+	return flag, trimmedArgs, lastArg, nil
+}
+
+// findFlag looks up the flag called name, which can be a shorthand, in the
+// flags of cmd, including those inherited from its parents.
+func findFlag(cmd *Cmd, name string) *pflag.Flag {
+	if len(name) == 1 {
+		cmd.mergeGlobalFlags()
+		return cmd.Flags().ShorthandLookup(name)
+	}
+
+	return cmd.lookupFlag(name)
+}
+
+// nonCompletableFlag determines if the flag should not be offered as a
+// completion.
+func nonCompletableFlag(flag *pflag.Flag) bool {
+	return flag.Hidden || len(flag.Deprecated) > 0
+}
+
+// string returns the names of the directives set in d.
+func (d ShellCompDirective) string() string {
+	var directives []string
+	if d&ShellCompDirectiveError != 0 {
+		directives = append(directives, "ShellCompDirectiveError")
+	}
+	if d&ShellCompDirectiveNoSpace != 0 {
+		directives = append(directives, "ShellCompDirectiveNoSpace")
+	}
+	if d&ShellCompDirectiveNoFileComp != 0 {
+		directives = append(directives, "ShellCompDirectiveNoFileComp")
+	}
+	if d&ShellCompDirectiveFilterFileExt != 0 {
+		directives = append(directives, "ShellCompDirectiveFilterFileExt")
+	}
+	if d&ShellCompDirectiveFilterDirs != 0 {
+		directives = append(directives, "ShellCompDirectiveFilterDirs")
+	}
+	if len(directives) == 0 {
+		directives = append(directives, "ShellCompDirectiveDefault")
+	}
+
+	if d >= shellCompDirectiveMaxValue {
+		return fmt.Sprintf("ERROR: unexpected ShellCompDirective value: %d", d)
+	}
+	return strings.Join(directives, ", ")
+}
+
+// CompDebug prints the specified string to the same file as where the
+// completion script prints its logs.
+// Note that completion printouts should never be on stdout as they would
+// be wrongly interpreted as actual completion choices by the completion script.
+func CompDebug(msg string, printToStdErr bool) {
+	msg = fmt.Sprintf("[Debug] %s", msg)
+
+	// Such logs are only printed when the user has set the environment
+	// variable BASH_COMP_DEBUG_FILE to the path of some file to be used.
+	if path := os.Getenv("BASH_COMP_DEBUG_FILE"); path != "" {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err == nil {
+			defer f.Close()
+			CheckWriteString(f, msg)
+		}
+	}
+
+	if printToStdErr {
+		// Must print to stderr for this not to be read by the completion script.
+		_, _ = fmt.Fprint(os.Stderr, msg)
+	}
+}
+
+// CompDebugln prints the specified string with a newline at the end
+// to the same file as where the completion script prints its logs.
+// Such logs are only printed when the user has set the environment
+// variable BASH_COMP_DEBUG_FILE to the path of some file to be used.
+func CompDebugln(msg string, printToStdErr bool) {
+	CompDebug(fmt.Sprintf("%s\n", msg), printToStdErr)
+}
+
+// CompError prints the specified completion message to stderr.
+func CompError(msg string) {
+	msg = fmt.Sprintf("[Error] %s", msg)
+	CompDebug(msg, true)
+}
+
+// CompErrorln prints the specified completion message to stderr with a newline at the end.
+func CompErrorln(msg string) {
+	CompError(fmt.Sprintf("%s\n", msg))
+}
+
+// NoFileCompletions can be used to disable file completion for commands
+// that should not trigger file completions.
+func NoFileCompletions(cmd *Cmd, args []string, toComplete string) ([]string, ShellCompDirective) {
+	return nil, ShellCompDirectiveNoFileComp
+}
+
+// InitDefaultCompletionCmd adds a default 'completion' command to c, with
+// a subcommand for each supported shell. It is called automatically by
+// executing the cmd. Ignored if c has no subcommands, already has a
+// 'completion' command or CompletionOptions.DisableDefaultCmd is set.
+func (c *Cmd) InitDefaultCompletionCmd() {
+	if c.CompletionOptions.DisableDefaultCmd || !c.HasSubCommands() {
+		return
+	}
+
+	for _, cmd := range c.commands {
+		if cmd.Name() == compCmdName || cmd.HasAlias(compCmdName) {
+			// A completion command is already available
+			return
+		}
+	}
+
+	haveNoDescFlag := !c.CompletionOptions.DisableNoDescFlag && !c.CompletionOptions.DisableDescriptions
+
+	completionCmd := &Cmd{
+		Use:   compCmdName,
+		Short: "Generate the autocompletion script for the specified shell",
+		Long: fmt.Sprintf(`Generate the autocompletion script for %[1]s for the specified shell.
+See each sub-command's help for details on how to use the generated script.
+`, c.Root().Name()),
+		Args:              NoArgs,
+		ValidArgsFunction: NoFileCompletions,
+		Hidden:            c.CompletionOptions.HiddenDefaultCmd,
+	}
+	c.Add(completionCmd)
+
+	noDesc := c.CompletionOptions.DisableDescriptions
+	newShellCmd := func(shell, long string, gen func(out io.Writer, includeDesc bool) error) *Cmd {
+		cmd := &Cmd{
+			Use:                   shell,
+			Short:                 fmt.Sprintf("Generate the autocompletion script for %s", shell),
+			Long:                  long,
+			Args:                  NoArgs,
+			ValidArgsFunction:     NoFileCompletions,
+			DisableFlagsInUseLine: true,
+		}
+		cmd.SetLifecycle(Lifecycle{
+			Run: func(cmd *Cmd, args []string) error {
+				return gen(cmd.OutputStream(), !noDesc)
+			},
+		})
+
+		if haveNoDescFlag {
+			cmd.Flags().BoolVar(&noDesc, compCmdNoDescFlagName, compCmdNoDescFlagDefault, compCmdNoDescFlagDesc)
+		}
+
+		return cmd
+	}
+
+	bash := newShellCmd("bash", fmt.Sprintf(`Generate the autocompletion script for the bash shell.
+
+This script depends on the 'bash-completion' package.
+If it is not installed already, you can install it via your OS's package manager.
+
+To load completions in your current shell session:
+
+	source <(%[1]s completion bash)
+
+To load completions for every new session, execute once:
+
+#### Linux:
+
+	%[1]s completion bash > /etc/bash_completion.d/%[1]s
+
+#### macOS:
+
+	%[1]s completion bash > $(brew --prefix)/etc/bash_completion.d/%[1]s
+
+You will need to start a new shell for this setup to take effect.
+`, c.Root().Name()), func(out io.Writer, includeDesc bool) error {
+		return c.Root().GenBashCompletion(out, includeDesc)
+	})
+
+	zsh := newShellCmd("zsh", fmt.Sprintf(`Generate the autocompletion script for the zsh shell.
+
+If shell completion is not already enabled in your environment you will need
+to enable it.  You can execute the following once:
+
+	echo "autoload -U compinit; compinit" >> ~/.zshrc
+
+To load completions in your current shell session:
+
+	source <(%[1]s completion zsh); compdef _%[1]s %[1]s
+
+To load completions for every new session, execute once:
+
+#### Linux:
+
+	%[1]s completion zsh > "${fpath[1]}/_%[1]s"
+
+#### macOS:
+
+	%[1]s completion zsh > $(brew --prefix)/share/zsh/site-functions/_%[1]s
+
+You will need to start a new shell for this setup to take effect.
+`, c.Root().Name()), func(out io.Writer, includeDesc bool) error {
+		if includeDesc {
+			return c.Root().GenZshCompletion(out)
+		}
+		return c.Root().GenZshCompletionNoDesc(out)
+	})
+
+	fish := newShellCmd("fish", fmt.Sprintf(`Generate the autocompletion script for the fish shell.
+
+To load completions in your current shell session:
+
+	%[1]s completion fish | source
+
+To load completions for every new session, execute once:
+
+	%[1]s completion fish > ~/.config/fish/completions/%[1]s.fish
+
+You will need to start a new shell for this setup to take effect.
+`, c.Root().Name()), func(out io.Writer, includeDesc bool) error {
+		return c.Root().GenFishCompletion(out, includeDesc)
+	})
+
+	powershell := newShellCmd("powershell", fmt.Sprintf(`Generate the autocompletion script for powershell.
+
+To load completions in your current shell session:
+
+	%[1]s completion powershell | Out-String | Invoke-Expression
+
+To load completions for every new session, add the output of the above command
+to your powershell profile.
+`, c.Root().Name()), func(out io.Writer, includeDesc bool) error {
+		return c.Root().GenPowerShellCompletion(out, includeDesc)
+	})
+
+	completionCmd.Add(bash, zsh, fish, powershell)
+}
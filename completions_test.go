@@ -0,0 +1,236 @@
+package fuelcell
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+// newCompletionTree builds "app" with a "deploy" command, a "get" command
+// completing its ValidArgs and a "delete" command completing its args with a
+// ValidArgsFunction.
+func newCompletionTree() *Cmd {
+	noop := func(*Cmd, []string) error { return nil }
+
+	root := &Cmd{Use: "app"}
+	root.GlobalFlags().Bool("verbose", false, "verbose output")
+
+	deploy := &Cmd{Use: "deploy", Short: "deploy the app"}
+	deploy.SetLifecycle(Lifecycle{Run: noop})
+	deploy.Flags().String("env", "", "target environment")
+
+	get := &Cmd{
+		Use:       "get",
+		Short:     "get resources",
+		ValidArgs: []string{"pods\tlist pods", "services\tlist services"},
+	}
+	get.SetLifecycle(Lifecycle{Run: noop})
+
+	del := &Cmd{Use: "delete", Short: "delete the app"}
+	del.SetLifecycle(Lifecycle{Run: noop})
+	del.ValidArgsFunction = func(_ *Cmd, args []string, _ string) ([]string, ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, ShellCompDirectiveNoFileComp
+		}
+		return []string{"app1", "app2"}, ShellCompDirectiveNoSpace
+	}
+
+	root.Add(deploy, get, del)
+	return root
+}
+
+type completionTest struct {
+	name      string
+	args      []string
+	want      []string
+	directive string
+}
+
+// runCompletionTests executes the completion requests of tests on the tree
+// returned by newTree and checks the completions, in any order, and the
+// directive.
+func runCompletionTests(t *testing.T, newTree func() *Cmd, tests []completionTest) {
+	t.Helper()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, out, errOut, err := executeC(newTree(), tt.args...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// the order of the completions is up to the shell.
+			got := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+			sort.Strings(got[:len(got)-1])
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("completions =\n%q\nwant\n%q", got, tt.want)
+			}
+
+			want := "Completion ended with directive: " + tt.directive
+			if !strings.Contains(errOut, want) {
+				t.Errorf("error stream = %q, want %q", errOut, want)
+			}
+		})
+	}
+}
+
+func TestComplete(t *testing.T) {
+	runCompletionTests(t, newCompletionTree, []completionTest{
+		{
+			name:      "subcommands",
+			args:      []string{ShellCompRequestCmd, "de"},
+			want:      []string{"delete\tdelete the app", "deploy\tdeploy the app", ":4"},
+			directive: "ShellCompDirectiveNoFileComp",
+		},
+		{
+			name:      "subcommands without descriptions",
+			args:      []string{ShellCompNoDescRequestCmd, "de"},
+			want:      []string{"delete", "deploy", ":4"},
+			directive: "ShellCompDirectiveNoFileComp",
+		},
+		{
+			name: "flag names",
+			args: []string{ShellCompRequestCmd, "deploy", "--"},
+			want: []string{
+				"--env\ttarget environment",
+				"--help\thelp for deploy",
+				"--verbose\tverbose output",
+				":4",
+			},
+			directive: "ShellCompDirectiveNoFileComp",
+		},
+		{
+			name:      "valid args",
+			args:      []string{ShellCompRequestCmd, "get", "p"},
+			want:      []string{"pods\tlist pods", ":4"},
+			directive: "ShellCompDirectiveNoFileComp",
+		},
+		{
+			name:      "valid args function",
+			args:      []string{ShellCompRequestCmd, "delete", ""},
+			want:      []string{"app1", "app2", ":2"},
+			directive: "ShellCompDirectiveNoSpace",
+		},
+		{
+			name:      "no more valid args function completions",
+			args:      []string{ShellCompRequestCmd, "delete", "app1", ""},
+			want:      []string{":4"},
+			directive: "ShellCompDirectiveNoFileComp",
+		},
+	})
+}
+
+func TestCompletionCmd(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    CompletionOptions
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{name: "bash", args: []string{"completion", "bash"}, want: "${words[0]} __complete ${args[*]}"},
+		{name: "bash without descriptions", args: []string{"completion", "bash", "--no-descriptions"}, want: "${words[0]} __completeNoDesc ${args[*]}"},
+		{name: "descriptions disabled", opts: CompletionOptions{DisableDescriptions: true}, args: []string{"completion", "bash"}, want: "${words[0]} __completeNoDesc ${args[*]}"},
+		{name: "zsh", args: []string{"completion", "zsh"}, want: "#compdef app"},
+		{name: "fish", args: []string{"completion", "fish"}, want: "complete -c app -e"},
+		{name: "powershell", args: []string{"completion", "powershell"}, want: "Register-ArgumentCompleter -CommandName 'app'"},
+		{name: "no description flag disabled", opts: CompletionOptions{DisableNoDescFlag: true}, args: []string{"completion", "bash", "--no-descriptions"}, wantErr: true},
+		{name: "default command disabled", opts: CompletionOptions{DisableDefaultCmd: true}, args: []string{"completion", "bash"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := newCompletionTree()
+			root.CompletionOptions = tt.opts
+			root.SilenceErrors = true
+
+			_, out, _, err := executeC(root, tt.args...)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !strings.Contains(out, tt.want) {
+				t.Errorf("script does not contain %q:\n%s", tt.want, out)
+			}
+		})
+	}
+}
+
+func TestCompletionCmd_Hidden(t *testing.T) {
+	for _, hidden := range []bool{false, true} {
+		root := newCompletionTree()
+		root.CompletionOptions.HiddenDefaultCmd = hidden
+
+		_, out, _, err := executeC(root, ShellCompNoDescRequestCmd, "c")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if listed := strings.Contains(out, "completion\n"); listed == hidden {
+			t.Errorf("hidden %v: completion command listed %v:\n%s", hidden, listed, out)
+		}
+	}
+}
+
+func TestRegisterFlagCompletionFunc(t *testing.T) {
+	newTree := func() *Cmd {
+		root := newCompletionTree()
+		root.GlobalFlags().String("region", "", "region of the app")
+		err := root.RegisterFlagCompletionFunc("region", func(*Cmd, []string, string) ([]string, ShellCompDirective) {
+			return []string{"eu", "us"}, ShellCompDirectiveNoFileComp
+		})
+		if err != nil {
+			t.Fatalf("RegisterFlagCompletionFunc failed: %v", err)
+		}
+
+		deploy, _, _ := root.Find([]string{"deploy"})
+		err = deploy.RegisterFlagCompletionFunc("env", func(_ *Cmd, _ []string, toComplete string) ([]string, ShellCompDirective) {
+			return []string{toComplete + "dev\tdevelopment", toComplete + "prod\tproduction"}, ShellCompDirectiveNoSpace
+		})
+		if err != nil {
+			t.Fatalf("RegisterFlagCompletionFunc failed: %v", err)
+		}
+
+		return root
+	}
+
+	runCompletionTests(t, newTree, []completionTest{
+		{
+			name:      "local flag",
+			args:      []string{ShellCompRequestCmd, "deploy", "--env", ""},
+			want:      []string{"dev\tdevelopment", "prod\tproduction", ":2"},
+			directive: "ShellCompDirectiveNoSpace",
+		},
+		{
+			name:      "local flag with an equal sign",
+			args:      []string{ShellCompNoDescRequestCmd, "deploy", "--env=x"},
+			want:      []string{"xdev", "xprod", ":2"},
+			directive: "ShellCompDirectiveNoSpace",
+		},
+		{
+			name:      "inherited global flag",
+			args:      []string{ShellCompRequestCmd, "deploy", "--region", ""},
+			want:      []string{"eu", "us", ":4"},
+			directive: "ShellCompDirectiveNoFileComp",
+		},
+	})
+
+	root := newTree()
+	deploy, _, _ := root.Find([]string{"deploy"})
+	noop := func(*Cmd, []string, string) ([]string, ShellCompDirective) { return nil, ShellCompDirectiveDefault }
+
+	if err := deploy.RegisterFlagCompletionFunc("bogus", noop); err == nil {
+		t.Error("registering an unknown flag did not fail")
+	}
+
+	if err := deploy.RegisterFlagCompletionFunc("env", noop); err == nil {
+		t.Error("registering a flag twice did not fail")
+	}
+}
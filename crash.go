@@ -0,0 +1,131 @@
+package fuelcell
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/rsb/failure"
+)
+
+// ControlCrashReportFn is the function signature used to write the crash
+// report of a recovered panic.
+type ControlCrashReportFn func(io.Writer, *PanicError) error
+
+// CrashOptions are the options to control the recovery of panics raised by
+// the Lifecycle events.
+type CrashOptions struct {
+	// Recover converts a panic raised by any of the Lifecycle events into a
+	// PanicError, instead of crashing the program, and writes a crash report
+	// to the error stream and to a file.
+	Recover bool
+	// DisableReport only converts the panic, no crash report is written.
+	DisableReport bool
+	// Dir is the directory crash reports are written to, it defaults to a
+	// directory named after the root command in os.UserCacheDir.
+	Dir string
+	// WriteReport writes the crash report, it defaults to WriteCrashReport.
+	WriteReport ControlCrashReportFn
+}
+
+// WriteCrashReport writes the panic value, the executed command, the Go
+// version, the build info and the stack trace of e.
+func WriteCrashReport(w io.Writer, e *PanicError) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "panic: %v\n\n", e.Value)
+	fmt.Fprintf(&b, "command: %s\n", e.Path)
+	fmt.Fprintf(&b, "args: %q\n", e.Args)
+	fmt.Fprintf(&b, "time: %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&b, "go: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		fmt.Fprintf(&b, "\n%s", info)
+	}
+
+	fmt.Fprintf(&b, "\n%s", e.Stack)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// newPanicError captures the stack trace of a panic, it must be called from
+// the deferred function that recovered v.
+func newPanicError(c *Cmd, args []string, v interface{}) *PanicError {
+	return &PanicError{
+		Path:  c.Path(),
+		Args:  args,
+		Value: v,
+		Stack: debug.Stack(),
+	}
+}
+
+// reportCrash writes the crash report of e to the error stream, then to a
+// file and tells the user where to find it. The report on the error stream
+// is kept when the file can not be written.
+func (c *Cmd) reportCrash(e *PanicError) {
+	root := c.Root()
+	opts := root.CrashOptions
+	if opts.DisableReport {
+		return
+	}
+
+	streams := c.dataStreams()
+	if err := opts.reportWriter()(streams.Error(), e); err != nil {
+		streams.PrintErrln(fmt.Sprintf("%s crashed, unable to write the crash report: %v", root.Name(), err))
+	}
+
+	file, err := opts.writeReport(root.Name(), e)
+	if err != nil {
+		streams.PrintErrln(fmt.Sprintf("%s crashed, unable to write the crash report file: %v", root.Name(), err))
+		return
+	}
+
+	e.Report = file
+	streams.PrintErrln(fmt.Sprintf("%s crashed, the crash report was written to %s", root.Name(), file))
+}
+
+// reportWriter returns WriteReport, falling back to WriteCrashReport.
+func (o CrashOptions) reportWriter() ControlCrashReportFn {
+	if o.WriteReport == nil {
+		return WriteCrashReport
+	}
+
+	return o.WriteReport
+}
+
+// writeReport creates a new crash report file for e and returns its path.
+func (o CrashOptions) writeReport(name string, e *PanicError) (string, error) {
+	dir := o.Dir
+	if dir == "" {
+		cache, err := os.UserCacheDir()
+		if err != nil {
+			return "", failure.ToSystem(err, "os.UserCacheDir failed")
+		}
+		dir = filepath.Join(cache, name)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", failure.ToSystem(err, "os.MkdirAll failed for (%s)", dir)
+	}
+
+	f, err := os.CreateTemp(dir, "crash-*.log")
+	if err != nil {
+		return "", failure.ToSystem(err, "os.CreateTemp failed for (%s)", dir)
+	}
+
+	if err := o.reportWriter()(f, e); err != nil {
+		_ = f.Close()
+		return "", failure.ToSystem(err, "write crash report failed for (%s)", f.Name())
+	}
+
+	if err := f.Close(); err != nil {
+		return "", failure.ToSystem(err, "f.Close failed for (%s)", f.Name())
+	}
+
+	return f.Name(), nil
+}
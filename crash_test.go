@@ -0,0 +1,181 @@
+package fuelcell
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExecuteC_RunPanic(t *testing.T) {
+	root := &Cmd{Use: "app", SilenceErrors: true}
+	root.SetLifecycle(Lifecycle{Run: func(*Cmd, []string) error { panic("boom") }})
+
+	_, _, errOut, err := executeC(root, "x")
+
+	var pe *PanicError
+	if !errors.As(err, &pe) {
+		t.Fatalf("error = %v, want a PanicError", err)
+	}
+
+	if pe.Path != "app" || pe.Value != "boom" || len(pe.Args) != 1 || len(pe.Stack) == 0 {
+		t.Errorf("PanicError = %+v, want path app, value boom, the args and a stack", pe)
+	}
+
+	if errOut != "" || pe.Report != "" {
+		t.Errorf("a crash report was written without CrashOptions.Recover: %q", errOut)
+	}
+}
+
+func TestCrashOptions_Recover(t *testing.T) {
+	panics := func(*Cmd, []string) error { panic("boom") }
+	panicsFinally := func(*Cmd, []string, error) error { panic("boom") }
+
+	tests := []struct {
+		name      string
+		args      []string
+		lifecycle Lifecycle
+		finally   bool
+	}{
+		{"GlobalPreRun", []string{"deploy"}, Lifecycle{GlobalPreRun: panics}, true},
+		{"PreRun", []string{"deploy"}, Lifecycle{PreRun: panics}, true},
+		{"Run", []string{"deploy"}, Lifecycle{Run: panics}, true},
+		{"PostRun", []string{"deploy"}, Lifecycle{PostRun: panics}, true},
+		{"GlobalPostRun", []string{"deploy"}, Lifecycle{GlobalPostRun: panics}, true},
+		{"Finally", []string{"deploy"}, Lifecycle{Finally: panicsFinally}, false},
+		{"Finally on a flag error", []string{"deploy", "--bogus"}, Lifecycle{Finally: panicsFinally}, false},
+		{"Finally on an args error", []string{"deploy", "a", "b"}, Lifecycle{Finally: panicsFinally}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			root := newTestTree(&got)
+			root.SilenceErrors = true
+			root.SilenceUsage = true
+			root.CrashOptions = CrashOptions{Recover: true, Dir: t.TempDir()}
+
+			finallyErr := errors.New("GlobalFinally did not run")
+			root.SetLifecycle(Lifecycle{GlobalFinally: func(_ *Cmd, _ []string, err error) error {
+				finallyErr = err
+				return err
+			}})
+
+			deploy, _, _ := root.Find([]string{"deploy"})
+			deploy.Args = MaximumNArgs(1)
+			if tt.lifecycle.Run == nil {
+				tt.lifecycle.Run = func(*Cmd, []string) error { return nil }
+			}
+			deploy.SetLifecycle(tt.lifecycle)
+
+			_, _, errOut, err := executeC(root, tt.args...)
+
+			var pe *PanicError
+			if !errors.As(err, &pe) || pe.Value != "boom" {
+				t.Fatalf("error = %v, want a PanicError", err)
+			}
+
+			if tt.finally && finallyErr != err {
+				t.Errorf("GlobalFinally received %v, want the PanicError", finallyErr)
+			}
+
+			if pe.Report == "" || !strings.Contains(errOut, "panic: boom") {
+				t.Errorf("no crash report was written:\n%s", errOut)
+			}
+		})
+	}
+}
+
+func TestCrashOptions_Report(t *testing.T) {
+	dir := t.TempDir()
+	notDir := filepath.Join(dir, "file")
+	if err := os.WriteFile(notDir, nil, 0o600); err != nil {
+		t.Fatalf("os.WriteFile failed: %v", err)
+	}
+
+	custom := func(w io.Writer, e *PanicError) error {
+		_, err := io.WriteString(w, "custom report of "+e.Path+"\n")
+		return err
+	}
+
+	tests := []struct {
+		name    string
+		opts    CrashOptions
+		stream  []string
+		file    string
+		noFile  bool
+		noTrace bool
+	}{
+		{
+			name:   "default report",
+			opts:   CrashOptions{Recover: true, Dir: dir},
+			stream: []string{"panic: boom", "command: app deploy", "go: ", "app crashed, the crash report was written to "},
+			file:   "panic: boom",
+		},
+		{
+			name:   "custom writer",
+			opts:   CrashOptions{Recover: true, Dir: dir, WriteReport: custom},
+			stream: []string{"custom report of app deploy", "app crashed, the crash report was written to "},
+			file:   "custom report of app deploy",
+		},
+		{
+			name:   "file can not be written",
+			opts:   CrashOptions{Recover: true, Dir: notDir},
+			stream: []string{"panic: boom", "app crashed, unable to write the crash report file: "},
+			noFile: true,
+		},
+		{
+			name:    "report disabled",
+			opts:    CrashOptions{Recover: true, DisableReport: true},
+			noFile:  true,
+			noTrace: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			root := newTestTree(&got)
+			root.SilenceErrors = true
+			root.CrashOptions = tt.opts
+
+			deploy, _, _ := root.Find([]string{"deploy"})
+			deploy.SetLifecycle(Lifecycle{Run: func(*Cmd, []string) error { panic("boom") }})
+
+			_, _, errOut, err := executeC(root, "deploy")
+
+			var pe *PanicError
+			if !errors.As(err, &pe) {
+				t.Fatalf("error = %v, want a PanicError", err)
+			}
+
+			for _, w := range tt.stream {
+				if !strings.Contains(errOut, w) {
+					t.Errorf("error stream does not contain %q:\n%s", w, errOut)
+				}
+			}
+
+			if tt.noTrace && errOut != "" {
+				t.Errorf("error stream = %q, want empty", errOut)
+			}
+
+			if tt.noFile {
+				if pe.Report != "" {
+					t.Errorf("report file = %q, want none", pe.Report)
+				}
+				return
+			}
+
+			content, err := os.ReadFile(pe.Report)
+			if err != nil {
+				t.Fatalf("reading the report file failed: %v", err)
+			}
+
+			if !strings.Contains(string(content), tt.file) {
+				t.Errorf("report file does not contain %q:\n%s", tt.file, content)
+			}
+		})
+	}
+}
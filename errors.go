@@ -0,0 +1,191 @@
+package fuelcell
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// RequiredFlagsError is returned when flags marked as required were not set
+// on the command line. Flags holds the names of the missing flags.
+type RequiredFlagsError struct {
+	Flags []string
+}
+
+func (e *RequiredFlagsError) Error() string {
+	return fmt.Sprintf(`required flag(s) "%s" not set`, strings.Join(e.Flags, `", "`))
+}
+
+// FlagGroupError is returned when the flags of a group were not used
+// according to the rule of the group, identified by Annotation. Group holds
+// the names of all the flags in the group while Flags holds the missing
+// flags of a group required together or the flags set in a mutually
+// exclusive group.
+type FlagGroupError struct {
+	Annotation string
+	Group      []string
+	Flags      []string
+}
+
+func (e *FlagGroupError) Error() string {
+	group := strings.Join(e.Group, " ")
+	switch e.Annotation {
+	case FlagGroupRequiredTogether:
+		return fmt.Sprintf("if any flags in the group [%s] are set they must all be set; missing %v", group, e.Flags)
+	case FlagGroupOneRequired:
+		return fmt.Sprintf("at least one of the flags in the group [%s] is required", group)
+	case FlagGroupMutuallyExclusive:
+		return fmt.Sprintf("if any flags in the group [%s] are set none of the others can be; %v were all set", group, e.Flags)
+	}
+
+	return fmt.Sprintf("invalid use of the flags in the group [%s]", group)
+}
+
+// PanicError is returned when a Lifecycle event panicked. Value is the value
+// given to panic and Stack the stack trace of the panicking goroutine. Path
+// and Args identify the executed command while Report is the file the crash
+// report was written to, empty when no report was written.
+type PanicError struct {
+	Path   string
+	Args   []string
+	Value  interface{}
+	Stack  []byte
+	Report string
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%s panicked: %v", e.Path, e.Value)
+}
+
+// Unwrap returns the value given to panic when it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// UnknownCommandError is returned when Command is not a subcommand of the
+// command at Path. Suggestions holds the subcommands close to Command.
+type UnknownCommandError struct {
+	Path        string
+	Command     string
+	Suggestions []string
+}
+
+func newUnknownCommandError(cmd *Cmd, arg string) *UnknownCommandError {
+	return &UnknownCommandError{
+		Path:        cmd.Path(),
+		Command:     arg,
+		Suggestions: cmd.findSuggestions(arg),
+	}
+}
+
+func (e *UnknownCommandError) Error() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("unknown command %q for %q", e.Command, e.Path))
+	if len(e.Suggestions) > 0 {
+		b.WriteString("\n\nDid you mean this?\n")
+		for _, s := range e.Suggestions {
+			b.WriteString(fmt.Sprintf("\t%v\n", s))
+		}
+	}
+
+	return b.String()
+}
+
+// InvalidArgError is returned when Arg is not one of the ValidArgs of the
+// command at Path.
+type InvalidArgError struct {
+	Path      string
+	Arg       string
+	ValidArgs []string
+}
+
+func (e *InvalidArgError) Error() string {
+	return fmt.Sprintf("invalid argument %q for %q", e.Arg, e.Path)
+}
+
+// ArgCountError is returned when the command at Path received a number of
+// args outside the expected range. Min and Max are the expected number of
+// args, Max is -1 when there is no upper bound.
+type ArgCountError struct {
+	Path     string
+	Min      int
+	Max      int
+	Received int
+}
+
+func (e *ArgCountError) Error() string {
+	switch {
+	case e.Max < 0:
+		return fmt.Sprintf("requires at least %d arg(s), only received %d", e.Min, e.Received)
+	case e.Min == e.Max:
+		return fmt.Sprintf("accepts %d arg(s), received %d", e.Max, e.Received)
+	case e.Min == 0:
+		return fmt.Sprintf("accepts at most %d arg(s), received %d", e.Max, e.Received)
+	}
+
+	return fmt.Sprintf("accepts between %d and %d arg(s), received %d", e.Min, e.Max, e.Received)
+}
+
+// ArgParseError is returned when the value Arg given to the positional
+// argument Name of the command at Path could not be parsed, see ArgSpec.
+type ArgParseError struct {
+	Path string
+	Name string
+	Arg  string
+	Err  error
+}
+
+func (e *ArgParseError) Error() string {
+	return fmt.Sprintf("invalid value %q for argument %q of %q: %v", e.Arg, e.Name, e.Path, e.Err)
+}
+
+// Unwrap returns the error of the parser.
+func (e *ArgParseError) Unwrap() error {
+	return e.Err
+}
+
+// UsageError wraps an error caused by an invalid use of the command line,
+// like an unknown command, an invalid flag or invalid args.
+type UsageError struct {
+	Err error
+}
+
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// toUsageError wraps err into a UsageError, nil stays nil.
+func toUsageError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &UsageError{Err: err}
+}
+
+// IsUsageError determines if err was caused by an invalid use of the command
+// line, those are UsageError, UnknownCommandError, InvalidArgError,
+// ArgCountError, ArgParseError, RequiredFlagsError and FlagGroupError.
+func IsUsageError(err error) bool {
+	var usageErr *UsageError
+	var unknownErr *UnknownCommandError
+	var invalidErr *InvalidArgError
+	var countErr *ArgCountError
+	var parseErr *ArgParseError
+	var requiredErr *RequiredFlagsError
+	var groupErr *FlagGroupError
+
+	return errors.As(err, &usageErr) ||
+		errors.As(err, &unknownErr) ||
+		errors.As(err, &invalidErr) ||
+		errors.As(err, &countErr) ||
+		errors.As(err, &parseErr) ||
+		errors.As(err, &requiredErr) ||
+		errors.As(err, &groupErr)
+}
@@ -0,0 +1,91 @@
+package fuelcell
+
+import (
+	"errors"
+	"os"
+
+	"github.com/rsb/failure"
+)
+
+// Exit codes used when ExitCodes does not set a code.
+const (
+	ExitOK    = 0
+	ExitError = 1
+	ExitUsage = 2
+)
+
+// ExitCodes maps the errors returned by the execution to the code the
+// process exits with. Usage errors are checked first followed by the failure
+// categories of github.com/rsb/failure, a code that is not set falls back to
+// Default.
+type ExitCodes struct {
+	// Usage is used for usage errors, see IsUsageError. It defaults to
+	// ExitUsage.
+	Usage        int
+	NotFound     int
+	InvalidParam int
+	Validation   int
+	Config       int
+	System       int
+	// Default is used for any other error. It defaults to ExitError.
+	Default int
+}
+
+// Code returns the exit code for err, ExitOK when err is nil.
+func (e ExitCodes) Code(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	if IsUsageError(err) {
+		return e.or(e.Usage, ExitUsage)
+	}
+
+	categories := []struct {
+		is   func(error) bool
+		code int
+	}{
+		{failure.IsNotFound, e.NotFound},
+		{failure.IsInvalidParam, e.InvalidParam},
+		{failure.IsValidation, e.Validation},
+		{failure.IsConfig, e.Config},
+		{failure.IsSystem, e.System},
+	}
+
+	// failure only looks at the cause of a chain built by failure.Wrap,
+	// walk the chain so errors wrapped with %w are categorized as well.
+	for next := err; next != nil; next = errors.Unwrap(next) {
+		for _, cat := range categories {
+			if cat.is(next) {
+				return e.or(cat.code, e.or(e.Default, ExitError))
+			}
+		}
+	}
+
+	return e.or(e.Default, ExitError)
+}
+
+func (e ExitCodes) or(code, fallback int) int {
+	if code == 0 {
+		return fallback
+	}
+
+	return code
+}
+
+// ExitCode returns the code the process should exit with for err, using the
+// ExitCodes of the root command.
+func (c *Cmd) ExitCode(err error) int {
+	return c.Root().ExitCodes.Code(err)
+}
+
+// ExecuteAndExit executes the root command and exits the process with the
+// code mapped from the returned error by the ExitCodes of the root command.
+func (c *Cmd) ExecuteAndExit() {
+	cmd, err := c.ExecuteC()
+	if cmd == nil {
+		cmd = c
+	}
+
+	os.Exit(cmd.ExitCode(err))
+}
@@ -0,0 +1,87 @@
+package fuelcell
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/rsb/failure"
+)
+
+func TestExitCodes_Code(t *testing.T) {
+	custom := ExitCodes{Usage: 64, NotFound: 3, InvalidParam: 4, Validation: 5, Config: 6, System: 7, Default: 9}
+
+	tests := []struct {
+		name  string
+		codes ExitCodes
+		err   error
+		want  int
+	}{
+		{"nil", ExitCodes{}, nil, ExitOK},
+		{"plain error", ExitCodes{}, errors.New("boom"), ExitError},
+		{"usage error", ExitCodes{}, toUsageError(errors.New("bad flag")), ExitUsage},
+		{"required flags", ExitCodes{}, &RequiredFlagsError{Flags: []string{"env"}}, ExitUsage},
+		{"unset category", ExitCodes{}, failure.NotFound("no app"), ExitError},
+		{"unset category with a default", ExitCodes{Default: 9}, failure.System("down"), 9},
+		{"custom usage", custom, toUsageError(errors.New("bad flag")), 64},
+		{"not found", custom, failure.NotFound("no app"), 3},
+		{"invalid param", custom, failure.InvalidParam("bad id"), 4},
+		{"validation", custom, failure.Validation("bad input"), 5},
+		{"config", custom, failure.Config("no config"), 6},
+		{"system", custom, failure.System("down"), 7},
+		{"wrapped by failure", custom, failure.Wrap(failure.Config("no config"), "load failed"), 6},
+		{"wrapped with %w", custom, fmt.Errorf("load failed: %w", failure.Config("no config")), 6},
+		{"custom default", custom, errors.New("boom"), 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.codes.Code(tt.err); got != tt.want {
+				t.Errorf("Code(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCmd_ExitCode(t *testing.T) {
+	var got []string
+	root := newTestTree(&got)
+	root.ExitCodes = ExitCodes{Config: 78}
+	deploy, _, _ := root.Find([]string{"deploy"})
+	deploy.ExitCodes = ExitCodes{Config: 1}
+
+	if code := deploy.ExitCode(failure.Config("no config")); code != 78 {
+		t.Errorf("ExitCode = %d, want the code of the root 78", code)
+	}
+
+	root.SilenceErrors = true
+	root.SilenceUsage = true
+	for _, args := range [][]string{{"deplyo"}, {"deploy", "--bogus"}} {
+		_, _, _, err := executeC(root, args...)
+		if code := root.ExitCode(err); code != ExitUsage {
+			t.Errorf("ExitCode for %q = %d, want %d", args, code, ExitUsage)
+		}
+	}
+}
+
+func TestExecuteAndExit(t *testing.T) {
+	if os.Getenv("FUELCELL_EXECUTE_AND_EXIT") == "1" {
+		root := &Cmd{Use: "app", SilenceErrors: true, ExitCodes: ExitCodes{NotFound: 44}}
+		root.SetLifecycle(Lifecycle{Run: func(*Cmd, []string) error {
+			return failure.NotFound("no app")
+		}})
+		root.SetArgs([]string{})
+		root.ExecuteAndExit()
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestExecuteAndExit$")
+	cmd.Env = append(os.Environ(), "FUELCELL_EXECUTE_AND_EXIT=1")
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 44 {
+		t.Fatalf("error = %v, want exit status 44", err)
+	}
+}
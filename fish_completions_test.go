@@ -0,0 +1,34 @@
+package fuelcell
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestGenFishCompletion(t *testing.T) {
+	tests := []struct {
+		name        string
+		includeDesc bool
+		want        string
+	}{
+		{"with descriptions", true, `set -l requestComp "$args[1] __complete $args[2..-1] $lastArg"`},
+		{"without descriptions", false, `set -l requestComp "$args[1] __completeNoDesc $args[2..-1] $lastArg"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			if err := newCompletionTree().GenFishCompletion(buf, tt.includeDesc); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			script := buf.String()
+			for _, w := range []string{tt.want, "complete -c app -e", "complete -c app -n '__app_prepare_completions'"} {
+				if !strings.Contains(script, w) {
+					t.Errorf("script does not contain %q", w)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package fuelcell
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/rsb/failure"
+	flag "github.com/spf13/pflag"
+)
+
+// Annotations used to record the flag groups a flag belongs to. The value
+// of each annotation is the list of groups, each group being the space
+// separated names of its flags.
+const (
+	FlagGroupRequiredTogether  = "fuelcell_annotation_required_if_others_set"
+	FlagGroupOneRequired       = "fuelcell_annotation_one_required"
+	FlagGroupMutuallyExclusive = "fuelcell_annotation_mutually_exclusive"
+)
+
+// MarkFlagsRequiredTogether marks the given flags with annotations so that
+// an error is reported if the command is invoked with a subset (but not
+// all) of the given flags.
+func (c *Cmd) MarkFlagsRequiredTogether(names ...string) error {
+	return c.markFlagGroup(FlagGroupRequiredTogether, names)
+}
+
+// MarkFlagsOneRequired marks the given flags with annotations so that an
+// error is reported if the command is invoked without at least one flag
+// from the given set of flags.
+func (c *Cmd) MarkFlagsOneRequired(names ...string) error {
+	return c.markFlagGroup(FlagGroupOneRequired, names)
+}
+
+// MarkFlagsMutuallyExclusive marks the given flags with annotations so that
+// an error is reported if the command is invoked with more than one flag
+// from the given set of flags.
+func (c *Cmd) MarkFlagsMutuallyExclusive(names ...string) error {
+	return c.markFlagGroup(FlagGroupMutuallyExclusive, names)
+}
+
+func (c *Cmd) markFlagGroup(annotation string, names []string) error {
+	c.mergeGlobalFlags()
+
+	flags := c.Flags()
+	for _, name := range names {
+		if flags.Lookup(name) == nil {
+			return failure.NotFound("flag %q does not exist, it can not be part of the group [%s]", name, strings.Join(names, " "))
+		}
+	}
+
+	group := strings.Join(names, " ")
+	for _, name := range names {
+		f := flags.Lookup(name)
+		if err := flags.SetAnnotation(name, annotation, append(f.Annotations[annotation], group)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateFlagGroups checks the flags set on the command line against the
+// groups they belong to.
+func (c *Cmd) validateFlagGroups() error {
+	if c.DisableFlagParsing {
+		return nil
+	}
+
+	for _, annotation := range []string{FlagGroupRequiredTogether, FlagGroupOneRequired, FlagGroupMutuallyExclusive} {
+		status := flagGroupStatus(c.Flags(), annotation)
+		for _, group := range sortedGroups(status) {
+			var set, unset []string
+			for name, isSet := range status[group] {
+				if isSet {
+					set = append(set, name)
+				} else {
+					unset = append(unset, name)
+				}
+			}
+			// Sort values, so they can be tested/scripted against consistently.
+			sort.Strings(set)
+			sort.Strings(unset)
+
+			err := &FlagGroupError{Annotation: annotation, Group: strings.Split(group, " ")}
+			switch annotation {
+			case FlagGroupRequiredTogether:
+				if len(set) > 0 && len(unset) > 0 {
+					err.Flags = unset
+					return err
+				}
+			case FlagGroupOneRequired:
+				if len(set) == 0 {
+					return err
+				}
+			case FlagGroupMutuallyExclusive:
+				if len(set) > 1 {
+					err.Flags = set
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// enforceFlagGroupsForCompletion changes the flags so completion follows
+// the groups: the other flags of a group required together are required
+// once one is set, all the flags of a one required group are required
+// while none is set, and the other flags of a mutually exclusive group are
+// hidden once one is set.
+func (c *Cmd) enforceFlagGroupsForCompletion() {
+	if c.DisableFlagParsing {
+		return
+	}
+
+	flags := c.Flags()
+	for group, status := range flagGroupStatus(flags, FlagGroupRequiredTogether) {
+		for _, isSet := range status {
+			if isSet {
+				for _, name := range strings.Split(group, " ") {
+					_ = MarkFlagRequired(flags, name)
+				}
+				break
+			}
+		}
+	}
+
+	for group, status := range flagGroupStatus(flags, FlagGroupOneRequired) {
+		set := false
+		for _, isSet := range status {
+			set = set || isSet
+		}
+
+		if !set {
+			for _, name := range strings.Split(group, " ") {
+				_ = MarkFlagRequired(flags, name)
+			}
+		}
+	}
+
+	for group, status := range flagGroupStatus(flags, FlagGroupMutuallyExclusive) {
+		for setName, isSet := range status {
+			if !isSet {
+				continue
+			}
+
+			// The flag that is set is not hidden because it may be an array
+			// or slice flag and therefore must continue being suggested
+			for _, name := range strings.Split(group, " ") {
+				if name != setName {
+					flags.Lookup(name).Hidden = true
+				}
+			}
+		}
+	}
+}
+
+// flagGroupStatus collects, for every group recorded under annotation, if
+// each of its flags was set. Groups with flags that are not defined in
+// flags are ignored.
+func flagGroupStatus(flags *flag.FlagSet, annotation string) map[string]map[string]bool {
+	status := map[string]map[string]bool{}
+	flags.VisitAll(func(f *flag.Flag) {
+		for _, group := range f.Annotations[annotation] {
+			if status[group] == nil {
+				names := strings.Split(group, " ")
+				if !hasAllFlags(flags, names...) {
+					continue
+				}
+
+				status[group] = map[string]bool{}
+				for _, name := range names {
+					status[group][name] = false
+				}
+			}
+			status[group][f.Name] = f.Changed
+		}
+	})
+
+	return status
+}
+
+func hasAllFlags(flags *flag.FlagSet, names ...string) bool {
+	for _, name := range names {
+		if flags.Lookup(name) == nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sortedGroups(status map[string]map[string]bool) []string {
+	groups := make([]string, 0, len(status))
+	for group := range status {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	return groups
+}
@@ -0,0 +1,92 @@
+package fuelcell
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// newGroupTree builds "app" with a "deploy" command whose --json and --yaml
+// flags are grouped with mark.
+func newGroupTree(t *testing.T, mark func(c *Cmd, names ...string) error) *Cmd {
+	root := newCompletionTree()
+	root.SilenceErrors = true
+	root.SilenceUsage = true
+
+	deploy, _, _ := root.Find([]string{"deploy"})
+	deploy.Flags().Bool("json", false, "json output")
+	deploy.Flags().Bool("yaml", false, "yaml output")
+	if err := mark(deploy, "json", "yaml"); err != nil {
+		t.Fatalf("marking the group failed: %v", err)
+	}
+
+	return root
+}
+
+func TestFlagGroups(t *testing.T) {
+	tests := []struct {
+		name       string
+		mark       func(c *Cmd, names ...string) error
+		args       []string
+		annotation string
+		flags      []string
+	}{
+		{"required together all set", (*Cmd).MarkFlagsRequiredTogether, []string{"deploy", "--json", "--yaml"}, "", nil},
+		{"required together none set", (*Cmd).MarkFlagsRequiredTogether, []string{"deploy"}, "", nil},
+		{"required together one set", (*Cmd).MarkFlagsRequiredTogether, []string{"deploy", "--json"}, FlagGroupRequiredTogether, []string{"yaml"}},
+		{"one required set", (*Cmd).MarkFlagsOneRequired, []string{"deploy", "--yaml"}, "", nil},
+		{"one required none set", (*Cmd).MarkFlagsOneRequired, []string{"deploy"}, FlagGroupOneRequired, nil},
+		{"mutually exclusive one set", (*Cmd).MarkFlagsMutuallyExclusive, []string{"deploy", "--json"}, "", nil},
+		{"mutually exclusive all set", (*Cmd).MarkFlagsMutuallyExclusive, []string{"deploy", "--json", "--yaml"}, FlagGroupMutuallyExclusive, []string{"json", "yaml"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, _, err := executeC(newGroupTree(t, tt.mark), tt.args...)
+			if tt.annotation == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var groupErr *FlagGroupError
+			if !errors.As(err, &groupErr) {
+				t.Fatalf("error = %v, want a FlagGroupError", err)
+			}
+
+			if groupErr.Annotation != tt.annotation || !reflect.DeepEqual(groupErr.Flags, tt.flags) {
+				t.Errorf("error = %+v, want annotation %s and flags %q", groupErr, tt.annotation, tt.flags)
+			}
+		})
+	}
+}
+
+func TestFlagGroups_UnknownFlag(t *testing.T) {
+	deploy := &Cmd{Use: "deploy"}
+	deploy.Flags().Bool("json", false, "json output")
+
+	if err := deploy.MarkFlagsMutuallyExclusive("json", "bogus"); err == nil {
+		t.Error("grouping an unknown flag did not fail")
+	}
+}
+
+func TestFlagGroups_Completion(t *testing.T) {
+	runCompletionTests(t, func() *Cmd { return newGroupTree(t, (*Cmd).MarkFlagsMutuallyExclusive) }, []completionTest{
+		{
+			name:      "exclusive flags hidden once one is set",
+			args:      []string{ShellCompNoDescRequestCmd, "deploy", "--json", "--"},
+			want:      []string{"--env", "--help", "--verbose", ":4"},
+			directive: "ShellCompDirectiveNoFileComp",
+		},
+	})
+
+	runCompletionTests(t, func() *Cmd { return newGroupTree(t, (*Cmd).MarkFlagsOneRequired) }, []completionTest{
+		{
+			name:      "one required flags suggested first",
+			args:      []string{ShellCompNoDescRequestCmd, "deploy", ""},
+			want:      []string{"--json", "--yaml", ":0"},
+			directive: "ShellCompDirectiveDefault",
+		},
+	})
+}
@@ -24,8 +24,13 @@ var templateFuncs = template.FuncMap{
 // turned on by default. To disable sorting, set it to false.
 var EnableCommandSorting = true
 
-// CheckErr prints the msg with the prefix [Error]: and exists with a
-// default code of 1 unless int is given as the 2nd param
+// EnablePrefixMatching allows an unambiguous prefix of a command name or
+// alias to be used in place of the full name. It is turned off by default.
+var EnablePrefixMatching = false
+
+// CheckErr prints the msg with the prefix [Error]: and exists with the code
+// given as the 2nd param. Without one, an error msg exits with the code of
+// the default ExitCodes and any other msg with ExitError.
 func CheckErr(msg interface{}, exit ...int) {
 	if msg == nil {
 		return
@@ -33,7 +38,11 @@ func CheckErr(msg interface{}, exit ...int) {
 
 	_, _ = fmt.Fprintln(os.Stderr, "[Error]:", msg)
 
-	code := 1
+	code := ExitError
+	if err, ok := msg.(error); ok {
+		code = ExitCodes{}.Code(err)
+	}
+
 	if len(exit) > 0 {
 		code = exit[0]
 	}
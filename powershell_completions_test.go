@@ -0,0 +1,34 @@
+package fuelcell
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestGenPowerShellCompletion(t *testing.T) {
+	tests := []struct {
+		name        string
+		includeDesc bool
+		want        string
+	}{
+		{"with descriptions", true, `$RequestComp="$Program __complete $Arguments"`},
+		{"without descriptions", false, `$RequestComp="$Program __completeNoDesc $Arguments"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			if err := newCompletionTree().GenPowerShellCompletion(buf, tt.includeDesc); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			script := buf.String()
+			for _, w := range []string{tt.want, "Register-ArgumentCompleter -CommandName 'app'"} {
+				if !strings.Contains(script, w) {
+					t.Errorf("script does not contain %q", w)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package fuelcell
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+)
+
+// DefaultForceQuitCode is the exit code used when a second signal is
+// received and SignalOptions.ForceQuitCode is not set.
+const DefaultForceQuitCode = 130
+
+// SignalOptions are the options to control the handling of os signals
+type SignalOptions struct {
+	// Handle cancels the context given to the Lifecycle events when the first
+	// SIGINT or SIGTERM is received. The GlobalPostRun event still runs so
+	// cleanup can happen, even when the interrupted event returns an error.
+	Handle bool
+	// ForceQuitCode is the code the process exits with when a second signal
+	// is received before the command has finished.
+	ForceQuitCode int
+}
+
+// ExitCode returns the code used to force quit, falling back to
+// DefaultForceQuitCode.
+func (o SignalOptions) ExitCode() int {
+	if o.ForceQuitCode == 0 {
+		return DefaultForceQuitCode
+	}
+
+	return o.ForceQuitCode
+}
+
+// notifySignals replaces the command context with one that is cancelled
+// on the first signal and exits the process on the second. The returned
+// func stops listening and restores the original context, on the command
+// and on every subcommand that was given the signal context.
+func (c *Cmd) notifySignals() (stop func()) {
+	parent := c.ctx
+	ctx, cancel := context.WithCancel(parent)
+	c.ctx = ctx
+	atomic.StoreInt32(&c.interrupted, 0)
+
+	ch := make(chan os.Signal, 2)
+	done := make(chan struct{})
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-ch:
+			atomic.AddInt32(&c.interrupted, 1)
+			cancel()
+		case <-done:
+			return
+		}
+
+		select {
+		case <-ch:
+			os.Exit(c.SignalOptions.ExitCode())
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(ch)
+		close(done)
+		cancel()
+		c.replaceContext(ctx, parent)
+	}
+}
+
+// replaceContext sets to on the command and every subcommand whose context
+// is from.
+func (c *Cmd) replaceContext(from, to context.Context) {
+	if c.ctx == from {
+		c.ctx = to
+	}
+
+	for _, cmd := range c.commands {
+		cmd.replaceContext(from, to)
+	}
+}
+
+// cancelOnSignal replaces the context set on the command with SetContext by
+// one that is also cancelled when signal is. The returned func restores the
+// original context.
+func (c *Cmd) cancelOnSignal(signal context.Context) (restore func()) {
+	parent := c.ctx
+	ctx, cancel := context.WithCancel(parent)
+	c.ctx = ctx
+
+	go func() {
+		select {
+		case <-signal.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return func() {
+		cancel()
+		if c.ctx == ctx {
+			c.ctx = parent
+		}
+	}
+}
+
+// isInterrupted determines if a signal was received while executing
+func (c *Cmd) isInterrupted() bool {
+	return atomic.LoadInt32(&c.interrupted) > 0
+}
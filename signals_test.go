@@ -0,0 +1,116 @@
+package fuelcell
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// interrupt sends SIGINT to the test process.
+func interrupt(t *testing.T) {
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("os.FindProcess failed: %v", err)
+	}
+
+	if err := p.Signal(os.Interrupt); err != nil {
+		t.Fatalf("p.Signal failed: %v", err)
+	}
+}
+
+// waitDone reports if ctx is cancelled within a second.
+func waitDone(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return true
+	case <-time.After(time.Second):
+		return false
+	}
+}
+
+func TestSignalOptions_Cancel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("interrupt can not be sent to the process on windows")
+	}
+
+	tests := []struct {
+		name   string
+		ownCtx bool
+	}{
+		{"root context", false},
+		{"context set with SetContext", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cancelled, postRun bool
+			root := &Cmd{Use: "app", SignalOptions: SignalOptions{Handle: true}}
+			child := &Cmd{Use: "child"}
+			child.SetLifecycle(Lifecycle{
+				Run: func(c *Cmd, _ []string) error {
+					interrupt(t)
+					cancelled = waitDone(c.Context())
+					return c.Context().Err()
+				},
+				GlobalPostRun: func(*Cmd, []string) error {
+					postRun = true
+					return nil
+				},
+			})
+			root.Add(child)
+
+			if tt.ownCtx {
+				child.SetContext(context.Background())
+			}
+
+			_, _, _, err := executeC(root, "child")
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("error = %v, want %v", err, context.Canceled)
+			}
+
+			if !cancelled {
+				t.Error("the context of the command was not cancelled")
+			}
+
+			if !postRun {
+				t.Error("GlobalPostRun did not run after the interrupt")
+			}
+
+			if root.Context().Err() != nil || child.Context().Err() != nil {
+				t.Error("the contexts were not restored once the execution ended")
+			}
+		})
+	}
+}
+
+func TestSignalOptions_ForceQuit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("interrupt can not be sent to the process on windows")
+	}
+
+	if os.Getenv("FUELCELL_FORCE_QUIT") == "1" {
+		root := &Cmd{Use: "app", SignalOptions: SignalOptions{Handle: true, ForceQuitCode: 42}}
+		root.SetLifecycle(Lifecycle{Run: func(c *Cmd, _ []string) error {
+			interrupt(t)
+			<-c.Context().Done()
+			interrupt(t)
+			time.Sleep(5 * time.Second)
+			return nil
+		}})
+		_, _, _, _ = executeC(root)
+		os.Exit(0)
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSignalOptions_ForceQuit$")
+	cmd.Env = append(os.Environ(), "FUELCELL_FORCE_QUIT=1")
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 42 {
+		t.Fatalf("error = %v, want exit status 42", err)
+	}
+}
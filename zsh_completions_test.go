@@ -0,0 +1,36 @@
+package fuelcell
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestGenZshCompletion(t *testing.T) {
+	tests := []struct {
+		name string
+		gen  func(*Cmd, *bytes.Buffer) error
+		want string
+	}{
+		{"with descriptions", func(c *Cmd, b *bytes.Buffer) error { return c.GenZshCompletion(b) }, `requestComp="${words[1]} __complete ${words[2,-1]}"`},
+		{"without descriptions", func(c *Cmd, b *bytes.Buffer) error { return c.GenZshCompletionNoDesc(b) }, `requestComp="${words[1]} __completeNoDesc ${words[2,-1]}"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			if err := tt.gen(newCompletionTree(), buf); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			script := buf.String()
+			if !strings.HasPrefix(script, "#compdef app\n") {
+				t.Errorf("script does not start with the compdef line:\n%s", script)
+			}
+
+			if !strings.Contains(script, tt.want) {
+				t.Errorf("script does not contain %q", tt.want)
+			}
+		})
+	}
+}